@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/config"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/perilpb"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+)
+
+const idleTimeout = time.Second
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: peril-dlq [flags] <command> [args]")
+	fmt.Fprintln(os.Stderr, "commands:")
+	fmt.Fprintln(os.Stderr, "  list                     show dead-lettered messages")
+	fmt.Fprintln(os.Stderr, "  replay <id>... | -all    republish messages to their original exchange")
+	fmt.Fprintln(os.Stderr, "  purge <id>... | -all     delete messages")
+	flag.PrintDefaults()
+}
+
+func main() {
+	brokerFlags := config.RegisterBrokerFlags(flag.CommandLine)
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	cfg, err := brokerFlags.Load()
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	dialOpts, err := cfg.DialOptions()
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	pubsub.RegisterCodec(perilpb.Codec{})
+
+	conn, err := pubsub.Dial(cfg.Broker.URL, dialOpts...)
+	if err != nil {
+		log.Fatalf("could not connect to RabbitMQ: %v", err)
+	}
+	defer conn.Close()
+
+	broker, err := pubsub.NewAMQPBroker(conn)
+	if err != nil {
+		log.Fatalf("could not create channel: %v", err)
+	}
+	defer broker.Close()
+
+	err = declareDeadLetterTopology(broker)
+	if err != nil {
+		log.Fatalf("could not declare dead letter topology: %v", err)
+	}
+
+	cmd, args := flag.Arg(0), flag.Args()[1:]
+	switch cmd {
+	case "list":
+		err = list(broker)
+	case "replay", "purge":
+		fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+		all := fs.Bool("all", false, "select every message")
+		fs.Parse(args)
+		if !*all && fs.NArg() == 0 {
+			log.Fatalf("%s: give message IDs or -all", cmd)
+		}
+		err = settle(broker, cmd == "replay", selector(*all, fs.Args()))
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func declareDeadLetterTopology(t pubsub.Topology) error {
+	err := t.DeclareExchange(pubsub.ExchangeSpec{
+		Name:    routing.ExchangePerilDeadLetter,
+		Kind:    pubsub.ExchangeFanout,
+		Durable: true,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = t.DeclareQueue(pubsub.QueueSpec{
+		Name:    routing.DeadLetterQueue,
+		Durable: true,
+	})
+	if err != nil {
+		return err
+	}
+	return t.BindQueue(routing.DeadLetterQueue, "", routing.ExchangePerilDeadLetter)
+}
+
+// drain holds every message currently in the dead letter queue without
+// acknowledging it. It stops once no delivery has arrived for idleTimeout.
+func drain(b pubsub.Broker) (pubsub.Consumer, []pubsub.Delivery, error) {
+	consumer, err := b.Consume(routing.DeadLetterQueue, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not consume dead letters: %v", err)
+	}
+
+	deliveries := []pubsub.Delivery{}
+	for {
+		select {
+		case d, ok := <-consumer.Deliveries():
+			if !ok {
+				return consumer, deliveries, nil
+			}
+			deliveries = append(deliveries, d)
+		case <-time.After(idleTimeout):
+			return consumer, deliveries, nil
+		}
+	}
+}
+
+func list(b pubsub.Broker) error {
+	consumer, deliveries, err := drain(b)
+	if err != nil {
+		return err
+	}
+	defer consumer.Close()
+
+	if len(deliveries) == 0 {
+		fmt.Println("The dead letter queue is empty.")
+	}
+	for i, d := range deliveries {
+		death := firstDeath(d.Message)
+		fmt.Printf("#%d %s\n", i+1, orNone(d.MessageID))
+		fmt.Printf("  reason:       %s\n", orNone(death.reason))
+		fmt.Printf("  died in:      %s\n", orNone(death.queue))
+		fmt.Printf("  original:     exchange=%s key=%s\n", orNone(death.exchange), orNone(death.routingKey))
+		fmt.Printf("  content type: %s\n", orNone(d.ContentType))
+		if sender, ok := d.Headers[pubsub.HeaderSender].(string); ok {
+			fmt.Printf("  sender:       %s\n", sender)
+		}
+		fmt.Printf("  body:         %s\n", describeBody(d.Message, death.routingKey))
+	}
+
+	for _, d := range deliveries {
+		d.Nack(true)
+	}
+	return nil
+}
+
+func settle(b pubsub.Broker, replay bool, selected func(pubsub.Delivery) bool) error {
+	consumer, deliveries, err := drain(b)
+	if err != nil {
+		return err
+	}
+	defer consumer.Close()
+
+	count := 0
+	for _, d := range deliveries {
+		if !selected(d) {
+			d.Nack(true)
+			continue
+		}
+		if replay {
+			death := firstDeath(d.Message)
+			if death.routingKey == "" && death.exchange == "" {
+				fmt.Printf("%s: no record of where it came from, leaving it\n", orNone(d.MessageID))
+				d.Nack(true)
+				continue
+			}
+			err := b.Publish(context.Background(), death.exchange, death.routingKey, replayMessage(d.Message))
+			if err != nil {
+				fmt.Printf("%s: could not replay: %v\n", orNone(d.MessageID), err)
+				d.Nack(true)
+				continue
+			}
+			fmt.Printf("%s: replayed to %s with key %s\n", orNone(d.MessageID), death.exchange, death.routingKey)
+		}
+		d.Ack()
+		count++
+	}
+
+	if replay {
+		fmt.Printf("Replayed %d message(s)\n", count)
+	} else {
+		fmt.Printf("Purged %d message(s)\n", count)
+	}
+	return nil
+}
+
+func selector(all bool, ids []string) func(pubsub.Delivery) bool {
+	wanted := map[string]struct{}{}
+	for _, id := range ids {
+		wanted[id] = struct{}{}
+	}
+	return func(d pubsub.Delivery) bool {
+		if all {
+			return true
+		}
+		_, ok := wanted[d.MessageID]
+		return ok
+	}
+}
+
+type death struct {
+	reason     string
+	queue      string
+	exchange   string
+	routingKey string
+}
+
+// firstDeath describes the first time the message was dead-lettered, which
+// is where it was originally published to.
+func firstDeath(msg pubsub.Message) death {
+	deaths, _ := msg.Headers["x-death"].([]any)
+	if len(deaths) == 0 {
+		return death{}
+	}
+	entry, _ := deaths[len(deaths)-1].(pubsub.Table)
+	d := death{}
+	d.reason, _ = entry["reason"].(string)
+	d.queue, _ = entry["queue"].(string)
+	d.exchange, _ = entry["exchange"].(string)
+	if keys, ok := entry["routing-keys"].([]any); ok && len(keys) > 0 {
+		d.routingKey, _ = keys[0].(string)
+	}
+	if exchange, ok := msg.Headers[pubsub.HeaderOriginalExchange].(string); ok {
+		d.exchange = exchange
+		d.routingKey, _ = msg.Headers[pubsub.HeaderOriginalRoutingKey].(string)
+	}
+	return d
+}
+
+func replayMessage(msg pubsub.Message) pubsub.Message {
+	headers := pubsub.Table{}
+	for k, v := range msg.Headers {
+		if k == "x-death" || strings.HasPrefix(k, "x-first-death") || strings.HasPrefix(k, "x-last-death") {
+			continue
+		}
+		headers[k] = v
+	}
+	msg.Headers = headers
+	return msg
+}
+
+func describeBody(msg pubsub.Message, routingKey string) string {
+	codec, err := pubsub.CodecFor(msg.ContentType)
+	if err != nil {
+		return fmt.Sprintf("%d bytes (%v)", len(msg.Body), err)
+	}
+
+	val := valueFor(routingKey)
+	if val == nil {
+		if _, ok := codec.(pubsub.JSONCodec); !ok {
+			return fmt.Sprintf("%d bytes of unknown type", len(msg.Body))
+		}
+		var generic any
+		val = &generic
+	}
+	err = codec.Unmarshal(msg.Body, val)
+	if err != nil {
+		return fmt.Sprintf("%d bytes (could not decode: %v)", len(msg.Body), err)
+	}
+	return fmt.Sprintf("%+v", val)
+}
+
+func valueFor(routingKey string) any {
+	prefix, _, _ := strings.Cut(routingKey, ".")
+	switch prefix {
+	case routing.ArmyMovesPrefix:
+		return &gamelogic.ArmyMove{}
+	case routing.WarRecognitionsPrefix:
+		return &gamelogic.RecognitionOfWar{}
+	case routing.CommandsPrefix:
+		return &gamelogic.Command{}
+	case routing.StatePrefix:
+		return &gamelogic.StateDelta{}
+	case routing.JoinPrefix:
+		return &gamelogic.JoinRequest{}
+	case routing.PauseKey:
+		return &routing.PlayingState{}
+	case routing.TurnKey:
+		return &routing.TurnState{}
+	case routing.GameOverKey:
+		return &routing.GameOver{}
+	case routing.GameLogSlug:
+		return &routing.GameLog{}
+	}
+	return nil
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
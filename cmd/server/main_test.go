@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/perilpb"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub/memory"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/topology"
+)
+
+// testClient is the part of the client that talks to the server: a local
+// view of the game kept up to date by state deltas, and requests.
+type testClient struct {
+	gs  *gamelogic.GameState
+	rpc *pubsub.RPCClient
+}
+
+func TestGame(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pubsub.RegisterCodec(perilpb.Codec{})
+
+	b := memory.New()
+	err := topology.Apply(b, topology.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = topology.Check(b, topology.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logSink := filepath.Join(t.TempDir(), "game.log")
+	world := gamelogic.NewWorld(gamelogic.WithSeed(1))
+	startServer(t, ctx, b, world, logSink)
+
+	alice := newTestClient(t, ctx, b, "alice")
+	bob := newTestClient(t, ctx, b, "bob")
+	aliceHome := alice.join(t, ctx)
+	bobHome := bob.join(t, ctx)
+	if aliceHome == bobHome {
+		t.Fatalf("both players start in %s", aliceHome)
+	}
+
+	alice.command(t, ctx, gamelogic.Command{Spawn: &gamelogic.SpawnIntent{Location: aliceHome, Rank: gamelogic.RankInfantry}})
+	bob.command(t, ctx, gamelogic.Command{Spawn: &gamelogic.SpawnIntent{Location: bobHome, Rank: gamelogic.RankArtillery}})
+	eventually(t, "alice sees her infantry", func() bool {
+		_, ok := alice.gs.GetUnit(1)
+		return ok
+	})
+
+	// artillery routs infantry
+	bob.command(t, ctx, gamelogic.Command{Move: &gamelogic.MoveIntent{UnitIDs: []int{1}, ToLocation: aliceHome}})
+	eventually(t, "alice loses her infantry", func() bool {
+		_, ok := alice.gs.GetUnit(1)
+		return !ok
+	})
+	eventually(t, "bob sees his artillery move", func() bool {
+		unit, ok := bob.gs.GetUnit(1)
+		return ok && unit.Location == aliceHome
+	})
+
+	want := "bob won a war against alice in " + string(aliceHome)
+	eventually(t, "the war is logged", func() bool {
+		data, _ := os.ReadFile(logSink)
+		return strings.Contains(string(data), want)
+	})
+}
+
+func TestSenderMustMatchRoutingKey(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := memory.New()
+	err := topology.Apply(b, topology.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	startServer(t, ctx, b, gamelogic.NewWorld(), filepath.Join(t.TempDir(), "game.log"))
+	mallory := newTestClient(t, ctx, b, "mallory")
+
+	tests := []struct {
+		name string
+		key  string
+		req  any
+	}{
+		{name: "join", key: routing.JoinPrefix + ".mallory", req: gamelogic.JoinRequest{}},
+		{name: "command", key: routing.CommandsPrefix + ".mallory", req: gamelogic.Command{
+			Spawn: &gamelogic.SpawnIntent{Location: "europe", Rank: gamelogic.RankInfantry},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(ctx, time.Second)
+			defer cancel()
+			_, err := pubsub.Request[any, any](ctx, mallory.rpc, routing.ExchangePerilTopic, tt.key, tt.req, pubsub.WithSender("alice"))
+			var remote *pubsub.RemoteError
+			if !errors.As(err, &remote) || remote.Code != pubsub.CodeUnauthorized {
+				t.Errorf("request as alice on %s: got %v, want %s", tt.key, err, pubsub.CodeUnauthorized)
+			}
+		})
+	}
+}
+
+// startServer subscribes the server's handlers the way main does.
+// TestSubscriptionsMatchTopology declares the server's queues, retry
+// queues included, on a broker provisioned from the default topology. The
+// memory broker refuses inequivalent redeclarations like RabbitMQ does, so
+// any drift between the two fails here.
+func TestSubscriptionsMatchTopology(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := memory.New()
+	err := topology.Apply(b, topology.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	startServer(t, ctx, b, gamelogic.NewWorld(), filepath.Join(t.TempDir(), "game.log"))
+	err = topology.Apply(b, topology.Default())
+	if err != nil {
+		t.Fatalf("applying the topology after the server subscribed: %v", err)
+	}
+}
+
+func startServer(t *testing.T, ctx context.Context, b pubsub.Broker, world *gamelogic.World, logSink string) {
+	t.Helper()
+	logSub, err := pubsub.Subscribe(ctx, b, routing.ExchangePerilTopic, routing.GameLogSlug, routing.GameLogSlug+".*", pubsub.DurableQueue, HandlerLog(logSink), pubsub.WithRetry(pubsub.DefaultRetryPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { logSub.Close() })
+
+	joinSub, err := pubsub.Serve(ctx, b, routing.ExchangePerilTopic, routing.JoinQueue, routing.JoinPrefix+".*", pubsub.DurableQueue, HandlerJoin(world, b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { joinSub.Close() })
+
+	commandSub, err := pubsub.Serve(ctx, b, routing.ExchangePerilTopic, routing.CommandsQueue, routing.CommandsPrefix+".*", pubsub.DurableQueue, HandlerCommand(world, b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { commandSub.Close() })
+}
+
+func newTestClient(t *testing.T, ctx context.Context, b pubsub.Broker, username string) *testClient {
+	t.Helper()
+	gs := gamelogic.NewGameState(username)
+	stateSub, err := pubsub.Subscribe(ctx, b, routing.ExchangePerilTopic, routing.StatePrefix+"."+username, routing.StatePrefix+".*", pubsub.TransientQueue, func(delta gamelogic.StateDelta) pubsub.Acktype {
+		gs.ApplyDelta(delta)
+		return pubsub.Ack
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { stateSub.Close() })
+
+	rpc, err := pubsub.NewRPCClient(b, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rpc.Close() })
+	return &testClient{gs: gs, rpc: rpc}
+}
+
+func (c *testClient) join(t *testing.T, ctx context.Context) gamelogic.Location {
+	t.Helper()
+	username := c.gs.GetUsername()
+	reply, err := pubsub.Request[gamelogic.JoinRequest, gamelogic.JoinReply](ctx, c.rpc, routing.ExchangePerilTopic, routing.JoinPrefix+"."+username, gamelogic.JoinRequest{}, pubsub.WithSender(username))
+	if err != nil {
+		t.Fatalf("%s could not join: %v", username, err)
+	}
+	c.gs.Join(reply)
+	return reply.Home
+}
+
+func (c *testClient) command(t *testing.T, ctx context.Context, cmd gamelogic.Command) {
+	t.Helper()
+	username := c.gs.GetUsername()
+	reply, err := pubsub.Request[gamelogic.Command, gamelogic.CommandReply](ctx, c.rpc, routing.ExchangePerilTopic, routing.CommandsPrefix+"."+username, cmd, pubsub.WithSender(username))
+	if err != nil {
+		t.Fatalf("%s: %v", username, err)
+	}
+	if !reply.Accepted {
+		t.Fatalf("%s: command rejected: %s", username, reply.Message)
+	}
+}
+
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name         string
+		file         string
+		env          map[string]string
+		args         []string
+		wantPrefetch int
+		wantURL      string
+	}{
+		{
+			name:         "defaults",
+			wantPrefetch: Default().Prefetch,
+			wantURL:      Default().Broker.URL,
+		},
+		{
+			name:         "file over defaults",
+			file:         `{"prefetch": 20, "broker": {"url": "amqp://file", "auth": "plain"}}`,
+			wantPrefetch: 20,
+			wantURL:      "amqp://file",
+		},
+		{
+			name:         "env over file",
+			file:         `{"prefetch": 20, "broker": {"url": "amqp://file", "auth": "plain"}}`,
+			env:          map[string]string{"PERIL_PREFETCH": "30"},
+			wantPrefetch: 30,
+			wantURL:      "amqp://file",
+		},
+		{
+			name:         "flags over env",
+			file:         `{"prefetch": 20, "broker": {"url": "amqp://file", "auth": "plain"}}`,
+			env:          map[string]string{"PERIL_PREFETCH": "30", "PERIL_BROKER_URL": "amqp://env"},
+			args:         []string{"-prefetch", "40"},
+			wantPrefetch: 40,
+			wantURL:      "amqp://env",
+		},
+		{
+			name:         "flags without file or env",
+			args:         []string{"-url", "amqp://flag"},
+			wantPrefetch: Default().Prefetch,
+			wantURL:      "amqp://flag",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, s := range append(append([]setting{}, brokerSettings...), gameSettings...) {
+				t.Setenv(s.env, "")
+				os.Unsetenv(s.env)
+			}
+			t.Setenv("PERIL_CONFIG", "")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if tt.file != "" {
+				path := filepath.Join(t.TempDir(), "peril.json")
+				if err := os.WriteFile(path, []byte(tt.file), 0644); err != nil {
+					t.Fatal(err)
+				}
+				t.Setenv("PERIL_CONFIG", path)
+			}
+
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			f := RegisterFlags(fs)
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := f.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Prefetch != tt.wantPrefetch {
+				t.Errorf("prefetch = %d, want %d", cfg.Prefetch, tt.wantPrefetch)
+			}
+			if cfg.Broker.URL != tt.wantURL {
+				t.Errorf("broker URL = %q, want %q", cfg.Broker.URL, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestValidateBroker(t *testing.T) {
+	dir := t.TempDir()
+	cert := filepath.Join(dir, "client.pem")
+	key := filepath.Join(dir, "client.key")
+	for _, path := range []string{cert, key} {
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		broker  Broker
+		wantErr string
+	}{
+		{
+			name:   "plain amqp",
+			broker: Broker{URL: "amqp://localhost", Auth: AuthPlain},
+		},
+		{
+			name:    "TLS settings on amqp",
+			broker:  Broker{URL: "amqp://localhost", Auth: AuthPlain, TLS: TLS{ServerName: "rabbit"}},
+			wantErr: "not amqps://",
+		},
+		{
+			name:   "TLS settings on amqps",
+			broker: Broker{URL: "amqps://localhost", Auth: AuthPlain, TLS: TLS{ServerName: "rabbit"}},
+		},
+		{
+			name:    "external auth without a client certificate",
+			broker:  Broker{URL: "amqps://localhost", Auth: AuthExternal},
+			wantErr: "needs a TLS client certificate",
+		},
+		{
+			name:   "external auth with a client certificate",
+			broker: Broker{URL: "amqps://localhost", Auth: AuthExternal, TLS: TLS{CertFile: cert, KeyFile: key}},
+		},
+		{
+			name:    "external auth with a password",
+			broker:  Broker{URL: "amqps://localhost", Auth: AuthExternal, TLS: TLS{CertFile: cert, KeyFile: key}, Password: "secret"},
+			wantErr: "does not use a username or password",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Broker = tt.broker
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("got %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package gamelogic
+
+import (
+	"math/rand"
+	"sort"
+)
+
+type Side int
+
+const (
+	SideAttacker Side = iota
+	SideDefender
+)
+
+// Battle is one war as a combat resolver sees it: the units each side has
+// in the contested location.
+type Battle struct {
+	Location Location
+	Attacker []Unit
+	Defender []Unit
+	Seed     int64
+}
+
+// CombatResolver decides how strongly each side fights. The side with the
+// higher power wins; equal power is a draw.
+type CombatResolver interface {
+	Power(b Battle) (attacker, defender int)
+}
+
+// DefaultResolver is the original rule: artillery is worth 10, cavalry 5
+// and infantry 1, with no luck involved.
+var DefaultResolver CombatResolver = PowerLevelResolver{}
+
+type PowerLevelResolver struct{}
+
+func (PowerLevelResolver) Power(b Battle) (int, int) {
+	return unitsToPowerLevel(b.Attacker), unitsToPowerLevel(b.Defender)
+}
+
+// DiceResolver rolls a die per unit and multiplies it into the unit's
+// power level. The dice are seeded from the battle, and units roll in ID
+// order, so the same battle always has the same outcome.
+type DiceResolver struct {
+	// Sides defaults to 6.
+	Sides int
+}
+
+func (r DiceResolver) Power(b Battle) (int, int) {
+	sides := r.Sides
+	if sides <= 0 {
+		sides = 6
+	}
+	rng := rand.New(rand.NewSource(b.Seed))
+	roll := func(units []Unit) int {
+		sorted := append([]Unit(nil), units...)
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i].ID < sorted[j].ID
+		})
+		power := 0
+		for _, unit := range sorted {
+			power += unitsToPowerLevel([]Unit{unit}) * (rng.Intn(sides) + 1)
+		}
+		return power
+	}
+	attacker := roll(b.Attacker)
+	defender := roll(b.Defender)
+	return attacker, defender
+}
+
+// CombatModifier adjusts one side's power after the resolver has worked
+// it out, e.g. for terrain.
+type CombatModifier func(loc Location, side Side, power int) int
+
+// DefenderBonus gives defenders in the listed locations extra power, in
+// percent.
+func DefenderBonus(percent int, locations ...Location) CombatModifier {
+	fortified := map[Location]struct{}{}
+	for _, loc := range locations {
+		fortified[loc] = struct{}{}
+	}
+	return func(loc Location, side Side, power int) int {
+		if _, ok := fortified[loc]; !ok || side != SideDefender {
+			return power
+		}
+		return power + power*percent/100
+	}
+}
+
+// TerrainBonus gives defenders the bonus of the terrain they hold on m.
+func TerrainBonus(m *Map) CombatModifier {
+	return func(loc Location, side Side, power int) int {
+		r, ok := m.Region(loc)
+		if !ok || side != SideDefender {
+			return power
+		}
+		return power + power*terrainDefence[r.Terrain]/100
+	}
+}
+
+// WithModifiers applies modifiers, in order, to the powers r works out.
+func WithModifiers(r CombatResolver, modifiers ...CombatModifier) CombatResolver {
+	return modifiedResolver{resolver: r, modifiers: modifiers}
+}
+
+type modifiedResolver struct {
+	resolver  CombatResolver
+	modifiers []CombatModifier
+}
+
+func (r modifiedResolver) Power(b Battle) (int, int) {
+	attacker, defender := r.resolver.Power(b)
+	for _, modify := range r.modifiers {
+		attacker = modify(b.Location, SideAttacker, attacker)
+		defender = modify(b.Location, SideDefender, defender)
+	}
+	return attacker, defender
+}
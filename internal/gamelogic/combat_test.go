@@ -0,0 +1,113 @@
+package gamelogic
+
+import (
+	"testing"
+)
+
+func testArmy(loc Location, ranks ...UnitRank) []Unit {
+	units := make([]Unit, len(ranks))
+	for i, rank := range ranks {
+		units[i] = Unit{ID: i + 1, Rank: rank, Location: loc}
+	}
+	return units
+}
+
+func TestPowerLevelResolver(t *testing.T) {
+	b := Battle{
+		Location: "europe",
+		Attacker: testArmy("europe", RankArtillery, RankCavalry, RankInfantry),
+		Defender: testArmy("europe", RankInfantry, RankInfantry),
+		Seed:     42,
+	}
+	attacker, defender := PowerLevelResolver{}.Power(b)
+	if attacker != 16 || defender != 2 {
+		t.Errorf("power = %d vs %d, want 16 vs 2", attacker, defender)
+	}
+}
+
+func TestDiceResolver(t *testing.T) {
+	army := testArmy("europe", RankArtillery, RankCavalry, RankInfantry)
+	tests := []struct {
+		name     string
+		resolver DiceResolver
+		seed     int64
+		attacker int
+		defender int
+	}{
+		{name: "default sides", resolver: DiceResolver{}, seed: 1, attacker: 86, defender: 71},
+		{name: "other seed", resolver: DiceResolver{}, seed: 2, attacker: 56, defender: 48},
+		{name: "d20", resolver: DiceResolver{Sides: 20}, seed: 1, attacker: 68, defender: 229},
+		{name: "one-sided die", resolver: DiceResolver{Sides: 1}, seed: 1, attacker: 16, defender: 16},
+	}
+	// the rolls come from math/rand, whose seeded sequences are stable
+	// across Go releases
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := Battle{Location: "europe", Attacker: army, Defender: army, Seed: tt.seed}
+			attacker, defender := tt.resolver.Power(b)
+			if attacker != tt.attacker || defender != tt.defender {
+				t.Errorf("power = %d vs %d, want %d vs %d", attacker, defender, tt.attacker, tt.defender)
+			}
+
+			// units roll in ID order, however they are listed
+			reversed := []Unit{army[2], army[1], army[0]}
+			b.Attacker, b.Defender = reversed, reversed
+			attacker, defender = tt.resolver.Power(b)
+			if attacker != tt.attacker || defender != tt.defender {
+				t.Errorf("reordered units: power = %d vs %d, want %d vs %d", attacker, defender, tt.attacker, tt.defender)
+			}
+		})
+	}
+}
+
+func TestDiceResolverBounds(t *testing.T) {
+	army := testArmy("europe", RankArtillery, RankCavalry, RankInfantry)
+	base := unitsToPowerLevel(army)
+	for seed := int64(0); seed < 100; seed++ {
+		attacker, defender := DiceResolver{}.Power(Battle{Attacker: army, Defender: army, Seed: seed})
+		for _, power := range []int{attacker, defender} {
+			if power < base || power > 6*base {
+				t.Fatalf("seed %d: power %d outside [%d, %d]", seed, power, base, 6*base)
+			}
+		}
+	}
+}
+
+func TestModifiers(t *testing.T) {
+	m, err := ParseMap([]byte(testMap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Regions[0].Terrain = TerrainMountains
+	m.index()
+
+	tests := []struct {
+		name     string
+		resolver CombatResolver
+		loc      Location
+		attacker int
+		defender int
+	}{
+		{name: "no modifiers", resolver: WithModifiers(PowerLevelResolver{}), loc: "west", attacker: 10, defender: 10},
+		{name: "fortified", resolver: WithModifiers(PowerLevelResolver{}, DefenderBonus(50, "west")), loc: "west", attacker: 10, defender: 15},
+		{name: "not fortified", resolver: WithModifiers(PowerLevelResolver{}, DefenderBonus(50, "east")), loc: "west", attacker: 10, defender: 10},
+		{name: "mountains", resolver: WithModifiers(PowerLevelResolver{}, TerrainBonus(m)), loc: "west", attacker: 10, defender: 15},
+		{name: "plains", resolver: WithModifiers(PowerLevelResolver{}, TerrainBonus(m)), loc: "east", attacker: 10, defender: 10},
+		{
+			name:     "in order",
+			resolver: WithModifiers(PowerLevelResolver{}, DefenderBonus(50, "west"), TerrainBonus(m)),
+			loc:      "west",
+			attacker: 10,
+			defender: 22,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			army := testArmy(tt.loc, RankArtillery)
+			attacker, defender := tt.resolver.Power(Battle{Location: tt.loc, Attacker: army, Defender: army})
+			if attacker != tt.attacker || defender != tt.defender {
+				t.Errorf("power = %d vs %d, want %d vs %d", attacker, defender, tt.attacker, tt.defender)
+			}
+		})
+	}
+}
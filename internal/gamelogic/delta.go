@@ -0,0 +1,99 @@
+package gamelogic
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ApplyDelta brings the local view of the game in line with a change the
+// server made, and tells the player what happened.
+func (gs *GameState) ApplyDelta(delta StateDelta) {
+	username := gs.GetUsername()
+	for _, account := range delta.Accounts {
+		if account.Username == username {
+			gs.setAccount(account)
+		}
+	}
+	owned := gs.ownedRegions()
+	gs.updateTerritory(delta.Control)
+	if delta.Move == nil && len(delta.Wars) == 0 && !delta.affects(username, owned) {
+		// another player's spawn or income
+		return
+	}
+	defer fmt.Println("------------------------")
+	fmt.Println()
+
+	if delta.Move != nil {
+		fmt.Println("==== Move Detected ====")
+		fmt.Printf("%s is moving %v unit(s) to %s\n", delta.Move.Player.Username, len(delta.Move.Units), delta.Move.ToLocation)
+		for _, unit := range delta.Move.Units {
+			fmt.Printf("* %v\n", unit.Rank)
+		}
+	}
+
+	for _, change := range delta.Players {
+		if change.Username != username {
+			continue
+		}
+		for _, unit := range change.Updated {
+			_, existed := gs.GetUnit(unit.ID)
+			gs.UpdateUnit(unit)
+			if !existed {
+				fmt.Printf("Spawned a(n) %s in %s with id %v\n", unit.Rank, unit.Location, unit.ID)
+			}
+		}
+		for _, id := range change.Removed {
+			gs.removeUnit(id)
+		}
+	}
+
+	for _, war := range delta.Wars {
+		fmt.Println("==== War Declared ====")
+		fmt.Printf("%s has declared war on %s in %s!\n", war.Attacker, war.Defender, war.Location)
+		fmt.Printf("Attacker has a power level of %v\n", war.AttackerPower)
+		fmt.Printf("Defender has a power level of %v\n", war.DefenderPower)
+		if war.IsDraw() {
+			fmt.Println("The war ended in a draw!")
+		} else {
+			fmt.Printf("%s has won the war!\n", war.Winner)
+		}
+		fmt.Println(war.CasualtyReport())
+	}
+
+	for _, t := range delta.Control {
+		switch {
+		case t.Owner == username:
+			fmt.Printf("You have taken control of %s.\n", t.Location)
+		case slices.Contains(owned, t.Location):
+			fmt.Printf("You have lost control of %s to %s.\n", t.Location, t.Owner)
+		}
+	}
+
+	for _, account := range delta.Accounts {
+		if account.Username == username && account.Collected > 0 {
+			fmt.Println("==== Income ====")
+			fmt.Printf("You collected %d and have %d in your treasury.\n", account.Collected, account.Treasury)
+		}
+	}
+}
+
+// affects reports whether the delta changes anything of username's, who
+// controlled owned before it.
+func (d StateDelta) affects(username string, owned []Location) bool {
+	for _, change := range d.Players {
+		if change.Username == username {
+			return true
+		}
+	}
+	for _, account := range d.Accounts {
+		if account.Username == username && account.Collected > 0 {
+			return true
+		}
+	}
+	for _, t := range d.Control {
+		if t.Owner == username || slices.Contains(owned, t.Location) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package gamelogic
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Account is a player's money. Income is what they will collect next
+// time, from the regions they control now; Collected is set on the delta
+// that paid it out.
+type Account struct {
+	Username  string
+	Treasury  int
+	Income    int
+	Collected int
+}
+
+func (w *World) incomes() map[string]int {
+	incomes := map[string]int{}
+	for loc, owner := range w.control {
+		r, _ := w.gameMap.Region(loc)
+		incomes[owner] += r.Yield
+	}
+	return incomes
+}
+
+func (w *World) account(username string, incomes map[string]int) Account {
+	return Account{
+		Username: username,
+		Treasury: w.treasury[username],
+		Income:   incomes[username],
+	}
+}
+
+// CollectIncome pays every player the yield of the regions they control.
+func (w *World) CollectIncome() StateDelta {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.collectIncome()
+}
+
+func (w *World) collectIncome() StateDelta {
+	incomes := w.incomes()
+	names := make([]string, 0, len(w.players))
+	for name := range w.players {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w.seq++
+	delta := StateDelta{Seq: w.seq}
+	for _, name := range names {
+		w.treasury[name] += incomes[name]
+		account := w.account(name, incomes)
+		account.Collected = incomes[name]
+		delta.Accounts = append(delta.Accounts, account)
+	}
+	return delta
+}
+
+// checkFunds rejects a spawn the player can not pay for, given that they
+// have already committed spent this turn.
+func (w *World) checkFunds(username string, rank UnitRank, spent int) error {
+	cost := w.gameMap.Costs[rank]
+	if left := w.treasury[username] - spent; cost > left {
+		return reject(RejectInsufficientFunds, "a(n) %s costs %d, you have %d", rank, cost, left)
+	}
+	return nil
+}
+
+// queuedSpending is what username's spawns queued this turn will cost.
+func (w *World) queuedSpending(username string) int {
+	spent := 0
+	for _, o := range w.orders {
+		if o.username == username && o.cmd.Spawn != nil {
+			spent += w.gameMap.Costs[o.cmd.Spawn.Rank]
+		}
+	}
+	return spent
+}
+
+func (gs *GameState) setAccount(a Account) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.account = a
+}
+
+func (gs *GameState) GetAccount() Account {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.account
+}
+
+// checkFunds is the client's early warning that the server will refuse a
+// spawn. In turn-based games it does not know about spawns already
+// queued, so the server may still refuse.
+func (gs *GameState) checkFunds(rank UnitRank) error {
+	cost := gs.GetMap().Costs[rank]
+	if treasury := gs.GetAccount().Treasury; cost > treasury {
+		return fmt.Errorf("a(n) %s costs %d, you have %d", rank, cost, treasury)
+	}
+	return nil
+}
@@ -28,6 +28,9 @@ type ArmyMove struct {
 type RecognitionOfWar struct {
 	Attacker Player
 	Defender Player
+	// Seed drives randomised combat, so everyone resolving the same war
+	// gets the same result.
+	Seed int64
 }
 
 type Location string
@@ -39,14 +42,3 @@ func getAllRanks() map[UnitRank]struct{} {
 		RankArtillery: {},
 	}
 }
-
-func getAllLocations() map[Location]struct{} {
-	return map[Location]struct{}{
-		"americas":   {},
-		"europe":     {},
-		"africa":     {},
-		"asia":       {},
-		"australia":  {},
-		"antarctica": {},
-	}
-}
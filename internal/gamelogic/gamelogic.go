@@ -2,6 +2,7 @@ package gamelogic
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"math/rand"
@@ -18,6 +19,7 @@ func PrintClientHelp() {
 	fmt.Println("    example:")
 	fmt.Println("    spawn europe infantry")
 	fmt.Println("* status")
+	fmt.Println("* map")
 	fmt.Println("* spam <n>")
 	fmt.Println("    example:")
 	fmt.Println("    spam 5")
@@ -58,6 +60,20 @@ func GetInput() []string {
 	return strings.Fields(line)
 }
 
+func GetInputContext(ctx context.Context) ([]string, error) {
+	inputs := make(chan []string, 1)
+	go func() {
+		inputs <- GetInput()
+	}()
+	select {
+	case words := <-inputs:
+		return words, nil
+	case <-ctx.Done():
+		fmt.Println()
+		return nil, ctx.Err()
+	}
+}
+
 func GetMaliciousLog() string {
 	possibleLogs := []string{
 		"Never interrupt your enemy when he is making a mistake.",
@@ -83,10 +99,30 @@ func (gs *GameState) CommandStatus() {
 	} else {
 		fmt.Println("The game is not paused.")
 	}
+	if err := gs.checkGameOver(); err != nil {
+		fmt.Printf("You can not give orders: %v.\n", err)
+	} else if err := gs.checkTurn(); err != nil {
+		fmt.Printf("You can not give orders: %v.\n", err)
+	}
 
 	p := gs.GetPlayerSnap()
 	fmt.Printf("You are %s, and you have %d units.\n", p.Username, len(p.Units))
 	for _, unit := range p.Units {
 		fmt.Printf("* %v: %v, %v\n", unit.ID, unit.Location, unit.Rank)
 	}
+	account := gs.GetAccount()
+	fmt.Printf("Treasury: %d, income: %d per collection.\n", account.Treasury, account.Income)
+	fmt.Printf("You control %v.\n", gs.ownedRegions())
+}
+
+func (gs *GameState) CommandMap() {
+	m := gs.GetMap()
+	fmt.Printf("Map: %s\n", m.Name)
+	for _, r := range m.Regions {
+		fmt.Printf("* %v (%v, yields %d), borders %v\n", r.Name, r.Terrain, r.Yield, m.neighbours(r.Name))
+	}
+	fmt.Println("Borders each rank can cross in one move, and its cost:")
+	for _, rank := range []UnitRank{RankInfantry, RankCavalry, RankArtillery} {
+		fmt.Printf("* %v: %d, costs %d\n", rank, m.Movement[rank], m.Costs[rank])
+	}
 }
@@ -0,0 +1,354 @@
+package gamelogic
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed maps/*.json
+var bundledMaps embed.FS
+
+const DefaultMapName = "continents"
+
+type Terrain string
+
+const (
+	TerrainPlains    Terrain = "plains"
+	TerrainDesert    Terrain = "desert"
+	TerrainTundra    Terrain = "tundra"
+	TerrainForest    Terrain = "forest"
+	TerrainMountains Terrain = "mountains"
+)
+
+// terrainDefence is the extra power, in percent, defenders get from the
+// terrain they hold.
+var terrainDefence = map[Terrain]int{
+	TerrainPlains:    0,
+	TerrainDesert:    0,
+	TerrainTundra:    0,
+	TerrainForest:    25,
+	TerrainMountains: 50,
+}
+
+// Map is the board: the regions units can stand in, which of them border
+// each other and how many borders each rank can cross in one move. It also
+// sets the economy: what each rank costs to spawn and the treasury every
+// player starts with.
+type Map struct {
+	Name     string             `json:"name"`
+	Movement map[UnitRank]int   `json:"movement"`
+	Costs    map[UnitRank]int   `json:"costs"`
+	Treasury int                `json:"treasury"`
+	Regions  []Region           `json:"regions"`
+	Start    []StartingPosition `json:"start"`
+
+	// adjacent is the border graph, with every edge in both directions
+	adjacent map[Location]map[Location]struct{}
+	regions  map[Location]Region
+}
+
+// Region is one location on the map. Borders are two-way, so listing a
+// neighbour on either side is enough. Yield is the income the region gives
+// whoever controls it.
+type Region struct {
+	Name     Location   `json:"name"`
+	Terrain  Terrain    `json:"terrain"`
+	Yield    int        `json:"yield"`
+	Adjacent []Location `json:"adjacent"`
+}
+
+// StartingPosition is a home region handed to a player when they join,
+// with the units they start out with there.
+type StartingPosition struct {
+	Location Location   `json:"location"`
+	Units    []UnitRank `json:"units"`
+}
+
+// DefaultMap returns the six continents Peril has always been played on.
+func DefaultMap() *Map {
+	m, err := BundledMap(DefaultMapName)
+	if err != nil {
+		panic(fmt.Sprintf("embedded map is invalid: %v", err))
+	}
+	return m
+}
+
+// BundledMaps lists the names of the maps built into Peril.
+func BundledMaps() []string {
+	entries, err := bundledMaps.ReadDir("maps")
+	if err != nil {
+		panic(fmt.Sprintf("embedded maps are missing: %v", err))
+	}
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = strings.TrimSuffix(entry.Name(), ".json")
+	}
+	return names
+}
+
+func BundledMap(name string) (*Map, error) {
+	data, err := bundledMaps.ReadFile(path.Join("maps", name+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("no bundled map named %s, try one of %s", name, strings.Join(BundledMaps(), ", "))
+	}
+	m, err := ParseMap(data)
+	if err != nil {
+		return nil, fmt.Errorf("bundled map %s: %v", name, err)
+	}
+	return m, nil
+}
+
+// FindMap returns the bundled map called name, or else loads name as a
+// map file.
+func FindMap(name string) (*Map, error) {
+	for _, bundled := range BundledMaps() {
+		if bundled == name {
+			return BundledMap(name)
+		}
+	}
+	return LoadMap(name)
+}
+
+func LoadMap(path string) (*Map, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read map file: %v", err)
+	}
+	m, err := ParseMap(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return m, nil
+}
+
+func ParseMap(data []byte) (*Map, error) {
+	m := &Map{}
+	err := m.UnmarshalJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// UnmarshalJSON validates the map as well as decoding it, so a map that
+// arrives over the wire is as safe to use as one from ParseMap.
+func (m *Map) UnmarshalJSON(data []byte) error {
+	// mapFile has Map's fields but not this method
+	type mapFile Map
+	err := json.Unmarshal(data, (*mapFile)(m))
+	if err != nil {
+		return fmt.Errorf("could not parse map: %v", err)
+	}
+	err = m.Validate()
+	if err != nil {
+		return err
+	}
+	m.index()
+	return nil
+}
+
+func (m *Map) Validate() error {
+	regions := map[Location]struct{}{}
+	for _, r := range m.Regions {
+		if r.Name == "" {
+			return fmt.Errorf("region without a name")
+		}
+		if _, ok := regions[r.Name]; ok {
+			return fmt.Errorf("region %s is declared twice", r.Name)
+		}
+		if _, ok := terrainDefence[r.Terrain]; !ok {
+			return fmt.Errorf("region %s has unknown terrain %q", r.Name, r.Terrain)
+		}
+		if r.Yield < 0 {
+			return fmt.Errorf("region %s has a negative yield", r.Name)
+		}
+		regions[r.Name] = struct{}{}
+	}
+	if len(regions) == 0 {
+		return fmt.Errorf("map has no regions")
+	}
+
+	for _, r := range m.Regions {
+		for _, next := range r.Adjacent {
+			if next == r.Name {
+				return fmt.Errorf("region %s borders itself", r.Name)
+			}
+			if _, ok := regions[next]; !ok {
+				return fmt.Errorf("region %s borders unknown region %s", r.Name, next)
+			}
+		}
+	}
+	if unreached := m.unreachable(); len(unreached) > 0 {
+		return fmt.Errorf("regions %v can not be reached from %s", unreached, m.Regions[0].Name)
+	}
+
+	if len(m.Start) == 0 {
+		return fmt.Errorf("map has no starting positions")
+	}
+	homes := map[Location]struct{}{}
+	for _, start := range m.Start {
+		if _, ok := regions[start.Location]; !ok {
+			return fmt.Errorf("starting position in unknown region %s", start.Location)
+		}
+		if _, ok := homes[start.Location]; ok {
+			return fmt.Errorf("region %s is a starting position twice", start.Location)
+		}
+		homes[start.Location] = struct{}{}
+		for _, rank := range start.Units {
+			if _, ok := getAllRanks()[rank]; !ok {
+				return fmt.Errorf("starting position %s has unknown unit %s", start.Location, rank)
+			}
+		}
+	}
+
+	for rank := range getAllRanks() {
+		hops, ok := m.Movement[rank]
+		if !ok {
+			return fmt.Errorf("no movement range for %s", rank)
+		}
+		if hops < 1 {
+			return fmt.Errorf("movement range for %s must be at least 1, got %d", rank, hops)
+		}
+	}
+	for rank := range m.Movement {
+		if _, ok := getAllRanks()[rank]; !ok {
+			return fmt.Errorf("movement range for unknown rank %s", rank)
+		}
+	}
+	for rank := range getAllRanks() {
+		cost, ok := m.Costs[rank]
+		if !ok {
+			return fmt.Errorf("no cost for %s", rank)
+		}
+		if cost < 0 {
+			return fmt.Errorf("cost of %s must not be negative, got %d", rank, cost)
+		}
+	}
+	for rank := range m.Costs {
+		if _, ok := getAllRanks()[rank]; !ok {
+			return fmt.Errorf("cost for unknown rank %s", rank)
+		}
+	}
+	if m.Treasury < 0 {
+		return fmt.Errorf("starting treasury must not be negative, got %d", m.Treasury)
+	}
+	return nil
+}
+
+// unreachable lists the regions with no path from the first one. It
+// works on the raw region list, so it can run before index.
+func (m *Map) unreachable() []Location {
+	graph := map[Location][]Location{}
+	for _, r := range m.Regions {
+		for _, next := range r.Adjacent {
+			graph[r.Name] = append(graph[r.Name], next)
+			graph[next] = append(graph[next], r.Name)
+		}
+	}
+	seen := map[Location]struct{}{m.Regions[0].Name: {}}
+	queue := []Location{m.Regions[0].Name}
+	for len(queue) > 0 {
+		loc := queue[0]
+		queue = queue[1:]
+		for _, next := range graph[loc] {
+			if _, ok := seen[next]; !ok {
+				seen[next] = struct{}{}
+				queue = append(queue, next)
+			}
+		}
+	}
+	unreached := []Location{}
+	for _, r := range m.Regions {
+		if _, ok := seen[r.Name]; !ok {
+			unreached = append(unreached, r.Name)
+		}
+	}
+	return unreached
+}
+
+func (m *Map) index() {
+	m.adjacent = make(map[Location]map[Location]struct{}, len(m.Regions))
+	m.regions = make(map[Location]Region, len(m.Regions))
+	for _, r := range m.Regions {
+		m.adjacent[r.Name] = map[Location]struct{}{}
+		m.regions[r.Name] = r
+	}
+	for _, r := range m.Regions {
+		for _, next := range r.Adjacent {
+			m.adjacent[r.Name][next] = struct{}{}
+			m.adjacent[next][r.Name] = struct{}{}
+		}
+	}
+}
+
+// neighbours lists the regions bordering loc in name order.
+func (m *Map) neighbours(loc Location) []Location {
+	next := make([]Location, 0, len(m.adjacent[loc]))
+	for n := range m.adjacent[loc] {
+		next = append(next, n)
+	}
+	sort.Slice(next, func(i, j int) bool {
+		return next[i] < next[j]
+	})
+	return next
+}
+
+// Region looks up a region by name.
+func (m *Map) Region(loc Location) (Region, bool) {
+	r, ok := m.regions[loc]
+	return r, ok
+}
+
+func (m *Map) HasLocation(loc Location) bool {
+	_, ok := m.adjacent[loc]
+	return ok
+}
+
+// Distance is the fewest borders a unit has to cross to get from one
+// location to the other. It reports false when there is no path.
+func (m *Map) Distance(from, to Location) (int, bool) {
+	if !m.HasLocation(from) || !m.HasLocation(to) {
+		return 0, false
+	}
+	dist := map[Location]int{from: 0}
+	queue := []Location{from}
+	for len(queue) > 0 {
+		loc := queue[0]
+		queue = queue[1:]
+		if loc == to {
+			return dist[loc], true
+		}
+		for next := range m.adjacent[loc] {
+			if _, seen := dist[next]; !seen {
+				dist[next] = dist[loc] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+	return 0, false
+}
+
+func (m *Map) validateLocation(loc Location) error {
+	if !m.HasLocation(loc) {
+		return reject(RejectUnknownLocation, "%s is not a valid location", loc)
+	}
+	return nil
+}
+
+// validatePath checks that unit can reach to in a single move.
+func (m *Map) validatePath(unit Unit, to Location) error {
+	hops, ok := m.Distance(unit.Location, to)
+	if !ok {
+		return reject(RejectOutOfRange, "unit %v can not reach %s from %s", unit.ID, to, unit.Location)
+	}
+	if hops > m.Movement[unit.Rank] {
+		return reject(RejectOutOfRange, "unit %v (%s) can cross %d border(s) in one move, %s is %d away",
+			unit.ID, unit.Rank, m.Movement[unit.Rank], to, hops)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package gamelogic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMapRejectsBrokenMaps(t *testing.T) {
+	tests := []struct {
+		name    string
+		old     string
+		new     string
+		wantErr string
+	}{
+		{
+			name:    "duplicate region",
+			old:     `{"name": "east", "terrain": "plains", "yield": 3}`,
+			new:     `{"name": "east", "terrain": "plains", "yield": 3}, {"name": "west", "terrain": "plains"}`,
+			wantErr: "region west is declared twice",
+		},
+		{
+			name:    "border to an unknown region",
+			old:     `"adjacent": ["east"]`,
+			new:     `"adjacent": ["east", "north"]`,
+			wantErr: "region middle borders unknown region north",
+		},
+		{
+			name:    "region bordering itself",
+			old:     `"adjacent": ["east"]`,
+			new:     `"adjacent": ["east", "middle"]`,
+			wantErr: "region middle borders itself",
+		},
+		{
+			name:    "disconnected region",
+			old:     `"adjacent": ["east"]`,
+			new:     `"adjacent": []`,
+			wantErr: "can not be reached from west",
+		},
+		{
+			name: "no starting positions",
+			old: `{"location": "west", "units": ["infantry"]},
+    {"location": "east", "units": ["infantry"]}`,
+			new:     ``,
+			wantErr: "map has no starting positions",
+		},
+		{
+			name:    "missing movement range",
+			old:     `"movement": {"infantry": 1, "cavalry": 1, "artillery": 1}`,
+			new:     `"movement": {"infantry": 1, "cavalry": 1}`,
+			wantErr: "no movement range for artillery",
+		},
+		{
+			name:    "missing cost",
+			old:     `"costs": {"infantry": 1, "cavalry": 3, "artillery": 5}`,
+			new:     `"costs": {"infantry": 1, "artillery": 5}`,
+			wantErr: "no cost for cavalry",
+		},
+	}
+
+	if _, err := ParseMap([]byte(testMap)); err != nil {
+		t.Fatalf("test map: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(testMap, tt.old) {
+				t.Fatalf("test map does not contain %s", tt.old)
+			}
+			data := strings.Replace(testMap, tt.old, tt.new, 1)
+			_, err := ParseMap([]byte(data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBundledMapsAreValid(t *testing.T) {
+	for _, name := range BundledMaps() {
+		if _, err := BundledMap(name); err != nil {
+			t.Errorf("bundled map %s: %v", name, err)
+		}
+	}
+}
@@ -2,12 +2,20 @@ package gamelogic
 
 import (
 	"sync"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
 )
 
 type GameState struct {
-	Player Player
-	Paused bool
-	mu     *sync.RWMutex
+	Player  Player
+	Paused  bool
+	gameMap *Map
+	// turn is nil unless the game is turn-based
+	turn      *routing.TurnState
+	account   Account
+	territory map[Location]string
+	over      *routing.GameOver
+	mu        *sync.RWMutex
 }
 
 func NewGameState(username string) *GameState {
@@ -16,11 +24,25 @@ func NewGameState(username string) *GameState {
 			Username: username,
 			Units:    map[int]Unit{},
 		},
-		Paused: false,
-		mu:     &sync.RWMutex{},
+		Paused:    false,
+		gameMap:   DefaultMap(),
+		territory: map[Location]string{},
+		mu:        &sync.RWMutex{},
 	}
 }
 
+func (gs *GameState) SetMap(m *Map) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.gameMap = m
+}
+
+func (gs *GameState) GetMap() *Map {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.gameMap
+}
+
 func (gs *GameState) resumeGame() {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
@@ -45,14 +67,10 @@ func (gs *GameState) addUnit(u Unit) {
 	gs.Player.Units[u.ID] = u
 }
 
-func (gs *GameState) removeUnitsInLocation(loc Location) {
+func (gs *GameState) removeUnit(id int) {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
-	for k, v := range gs.Player.Units {
-		if v.Location == loc {
-			delete(gs.Player.Units, k)
-		}
-	}
+	delete(gs.Player.Units, id)
 }
 
 func (gs *GameState) UpdateUnit(u Unit) {
@@ -0,0 +1,97 @@
+package gamelogic
+
+import (
+	"fmt"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+)
+
+// JoinRequest is what a client sends when it starts. The player is the
+// sender of the request.
+type JoinRequest struct{}
+
+// JoinReply gives a joining player the map being played on, their home
+// region and the units they already have, so a client that restarts picks
+// up where it left off. Turn is nil unless the game is turn-based, and
+// GameOver is nil until the game has ended.
+type JoinReply struct {
+	Map       *Map
+	Home      Location
+	Player    Player
+	Paused    bool
+	Turn      *routing.TurnState
+	Account   Account
+	Territory []Territory
+	GameOver  *routing.GameOver
+}
+
+// Join adds username to the game. A new player is handed the next
+// starting position and its units, which are reported in the delta; once
+// every position is taken, they are handed out again from the first. A
+// player who has joined before gets their existing home and an empty
+// delta.
+func (w *World) Join(username string) (JoinReply, StateDelta) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var delta StateDelta
+	home, ok := w.homes[username]
+	if !ok {
+		start := w.gameMap.Start[len(w.homes)%len(w.gameMap.Start)]
+		home = start.Location
+		w.homes[username] = home
+
+		p := w.player(username)
+		spawned := []Unit{}
+		for _, rank := range start.Units {
+			w.nextIDs[username]++
+			unit := Unit{ID: w.nextIDs[username], Rank: rank, Location: home}
+			p.Units[unit.ID] = unit
+			spawned = append(spawned, unit)
+		}
+		if len(spawned) > 0 {
+			w.seq++
+			delta = w.settle(StateDelta{
+				Seq:     w.seq,
+				Players: []PlayerDelta{{Username: username, Updated: spawned}},
+			})
+		}
+	}
+
+	return JoinReply{
+		Map:       w.gameMap,
+		Home:      home,
+		Player:    w.snapshot(w.player(username)),
+		Paused:    w.paused,
+		Turn:      w.currentTurn(),
+		Account:   w.account(username, w.incomes()),
+		Territory: w.territory(),
+		GameOver:  w.over,
+	}, delta
+}
+
+// Join sets up the local view of the game from the server's reply.
+func (gs *GameState) Join(reply JoinReply) {
+	gs.SetMap(reply.Map)
+	for _, unit := range reply.Player.Units {
+		gs.UpdateUnit(unit)
+	}
+	if reply.Paused {
+		gs.pauseGame()
+	} else {
+		gs.resumeGame()
+	}
+	if reply.Turn != nil {
+		gs.setTurn(*reply.Turn)
+	}
+	gs.setAccount(reply.Account)
+	gs.updateTerritory(reply.Territory)
+	fmt.Printf("You are playing on %s. Your home is %s", reply.Map.Name, reply.Home)
+	if len(reply.Player.Units) > 0 {
+		fmt.Printf(", where you have %d unit(s)", len(reply.Player.Units))
+	}
+	fmt.Println(".")
+	if reply.GameOver != nil {
+		gs.HandleGameOver(*reply.GameOver)
+	}
+}
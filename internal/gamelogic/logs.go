@@ -2,6 +2,7 @@ package gamelogic
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"time"
@@ -14,19 +15,32 @@ const logsFile = "game.log"
 const writeToDiskSleep = 1 * time.Second
 
 func WriteLog(gamelog routing.GameLog) error {
+	return WriteLogFile(logsFile, gamelog)
+}
+
+func WriteLogFile(path string, gamelog routing.GameLog) error {
 	log.Printf("received game log...")
 	time.Sleep(writeToDiskSleep)
 
-	f, err := os.OpenFile(logsFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return fmt.Errorf("could not open logs file: %v", err)
 	}
 	defer f.Close()
 
-	str := fmt.Sprintf("%v %v: %v\n", gamelog.CurrentTime.Format(time.RFC3339), gamelog.Username, gamelog.Message)
-	_, err = f.WriteString(str)
+	err = writeLog(f, gamelog)
 	if err != nil {
 		return fmt.Errorf("could not write to logs file: %v", err)
 	}
 	return nil
 }
+
+func PrintLog(gamelog routing.GameLog) error {
+	return writeLog(os.Stdout, gamelog)
+}
+
+func writeLog(w io.Writer, gamelog routing.GameLog) error {
+	str := fmt.Sprintf("%v %v: %v\n", gamelog.CurrentTime.Format(time.RFC3339), gamelog.Username, gamelog.Message)
+	_, err := io.WriteString(w, str)
+	return err
+}
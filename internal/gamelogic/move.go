@@ -6,36 +6,10 @@ import (
 	"strconv"
 )
 
-type MoveOutcome int
-
-const (
-	MoveOutcomeSamePlayer MoveOutcome = iota
-	MoveOutComeSafe
-	MoveOutcomeMakeWar
-)
-
-func (gs *GameState) HandleMove(move ArmyMove) MoveOutcome {
-	defer fmt.Println("------------------------")
-	player := gs.GetPlayerSnap()
-
-	fmt.Println()
-	fmt.Println("==== Move Detected ====")
-	fmt.Printf("%s is moving %v unit(s) to %s\n", move.Player.Username, len(move.Units), move.ToLocation)
-	for _, unit := range move.Units {
-		fmt.Printf("* %v\n", unit.Rank)
-	}
-
-	if player.Username == move.Player.Username {
-		return MoveOutcomeSamePlayer
-	}
-
-	overlappingLocation := getOverlappingLocation(player, move.Player)
-	if overlappingLocation != "" {
-		fmt.Printf("You have units in %s! You are at war with %s!\n", overlappingLocation, move.Player.Username)
-		return MoveOutcomeMakeWar
-	}
-	fmt.Printf("You are safe from %s's units.\n", move.Player.Username)
-	return MoveOutComeSafe
+// MoveIntent asks the server to move some of the sending player's units.
+type MoveIntent struct {
+	UnitIDs    []int
+	ToLocation Location
 }
 
 func getOverlappingLocation(p1 Player, p2 Player) Location {
@@ -49,44 +23,53 @@ func getOverlappingLocation(p1 Player, p2 Player) Location {
 	return ""
 }
 
-func (gs *GameState) CommandMove(words []string) (ArmyMove, error) {
+// CommandMove parses a move command into an intent for the server. It
+// checks what it can against the local view of the game, but the units
+// only move once the server's state delta says so.
+func (gs *GameState) CommandMove(words []string) (MoveIntent, error) {
 	if gs.isPaused() {
-		return ArmyMove{}, errors.New("the game is paused, you can not move units")
+		return MoveIntent{}, errors.New("the game is paused, you can not move units")
+	}
+	err := gs.checkGameOver()
+	if err != nil {
+		return MoveIntent{}, err
+	}
+	err = gs.checkTurn()
+	if err != nil {
+		return MoveIntent{}, err
 	}
 	if len(words) < 3 {
-		return ArmyMove{}, errors.New("usage: move <location> <unitID> <unitID> <unitID> etc")
+		return MoveIntent{}, errors.New("usage: move <location> <unitID> <unitID> <unitID> etc")
 	}
+	gameMap := gs.GetMap()
 	newLocation := Location(words[1])
-	locations := getAllLocations()
-	if _, ok := locations[newLocation]; !ok {
-		return ArmyMove{}, fmt.Errorf("error: %s is not a valid location", newLocation)
+	err = gameMap.validateLocation(newLocation)
+	if err != nil {
+		return MoveIntent{}, fmt.Errorf("error: %w", err)
 	}
 	unitIDs := []int{}
 	for _, word := range words[2:] {
 		id := word
 		unitID, err := strconv.Atoi(id)
 		if err != nil {
-			return ArmyMove{}, fmt.Errorf("error: %s is not a valid unit ID", id)
+			return MoveIntent{}, fmt.Errorf("error: %s is not a valid unit ID", id)
 		}
 		unitIDs = append(unitIDs, unitID)
 	}
 
-	newUnits := []Unit{}
 	for _, unitID := range unitIDs {
 		unit, ok := gs.GetUnit(unitID)
 		if !ok {
-			return ArmyMove{}, fmt.Errorf("error: unit with ID %v not found", unitID)
+			return MoveIntent{}, fmt.Errorf("error: unit with ID %v not found", unitID)
+		}
+		err := gameMap.validatePath(unit, newLocation)
+		if err != nil {
+			return MoveIntent{}, fmt.Errorf("error: %w", err)
 		}
-		unit.Location = newLocation
-		gs.UpdateUnit(unit)
-		newUnits = append(newUnits, unit)
 	}
 
-	mv := ArmyMove{
+	return MoveIntent{
+		UnitIDs:    unitIDs,
 		ToLocation: newLocation,
-		Units:      newUnits,
-		Player:     gs.GetPlayerSnap(),
-	}
-	fmt.Printf("Moved %v units to %s\n", len(mv.Units), mv.ToLocation)
-	return mv, nil
+	}, nil
 }
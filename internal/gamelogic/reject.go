@@ -0,0 +1,49 @@
+package gamelogic
+
+import (
+	"fmt"
+)
+
+type RejectReason string
+
+const (
+	RejectInvalidCommand    RejectReason = "invalid_command"
+	RejectUnknownLocation   RejectReason = "unknown_location"
+	RejectUnknownRank       RejectReason = "unknown_rank"
+	RejectNotOwned          RejectReason = "not_owned"
+	RejectOutOfRange        RejectReason = "out_of_range"
+	RejectPaused            RejectReason = "paused"
+	RejectNotYourTurn       RejectReason = "not_your_turn"
+	RejectInsufficientFunds RejectReason = "insufficient_funds"
+	RejectGameOver          RejectReason = "game_over"
+)
+
+// RejectedError is returned when the rules do not allow a command. The
+// server sends the reason back to the player who issued it.
+type RejectedError struct {
+	Reason  RejectReason
+	Message string
+}
+
+func (e *RejectedError) Error() string {
+	return e.Message
+}
+
+func reject(reason RejectReason, format string, args ...any) error {
+	return &RejectedError{
+		Reason:  reason,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// CommandReply tells a player whether the server carried out a command.
+// Seq is the sequence number of the resulting state delta when accepted.
+// In turn-based games accepted commands are Queued instead, and carried out
+// when the turn ends.
+type CommandReply struct {
+	Accepted bool
+	Queued   bool
+	Seq      int
+	Reason   RejectReason
+	Message  string
+}
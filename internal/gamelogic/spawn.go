@@ -5,30 +5,51 @@ import (
 	"fmt"
 )
 
-func (gs *GameState) CommandSpawn(words []string) error {
+// SpawnIntent asks the server to create a unit for the sending player.
+type SpawnIntent struct {
+	Location Location
+	Rank     UnitRank
+}
+
+// CommandSpawn parses a spawn command into an intent for the server. The
+// unit only exists once the server's state delta says so.
+func (gs *GameState) CommandSpawn(words []string) (SpawnIntent, error) {
 	if len(words) < 3 {
-		return errors.New("usage: spawn <location> <rank>")
+		return SpawnIntent{}, errors.New("usage: spawn <location> <rank>")
 	}
-
-	locationName := words[1]
-	locations := getAllLocations()
-	if _, ok := locations[Location(locationName)]; !ok {
-		return fmt.Errorf("error: %s is not a valid location", locationName)
+	err := gs.checkGameOver()
+	if err != nil {
+		return SpawnIntent{}, err
+	}
+	err = gs.checkTurn()
+	if err != nil {
+		return SpawnIntent{}, err
 	}
 
-	rank := words[2]
-	units := getAllRanks()
-	if _, ok := units[UnitRank(rank)]; !ok {
-		return fmt.Errorf("error: %s is not a valid unit", rank)
+	intent := SpawnIntent{
+		Location: Location(words[1]),
+		Rank:     UnitRank(words[2]),
+	}
+	err = validateSpawn(gs.GetMap(), intent)
+	if err != nil {
+		return SpawnIntent{}, fmt.Errorf("error: %w", err)
 	}
+	err = gs.checkFunds(intent.Rank)
+	if err != nil {
+		return SpawnIntent{}, fmt.Errorf("error: %w", err)
+	}
+	return intent, nil
+}
 
-	id := len(gs.getUnitsSnap()) + 1
-	gs.addUnit(Unit{
-		ID:       id,
-		Rank:     UnitRank(rank),
-		Location: Location(locationName),
-	})
+func validateSpawn(m *Map, intent SpawnIntent) error {
+	err := m.validateLocation(intent.Location)
+	if err != nil {
+		return err
+	}
 
-	fmt.Printf("Spawned a(n) %s in %s with id %v\n", rank, locationName, id)
+	units := getAllRanks()
+	if _, ok := units[intent.Rank]; !ok {
+		return reject(RejectUnknownRank, "%s is not a valid unit", intent.Rank)
+	}
 	return nil
 }
@@ -0,0 +1,281 @@
+package gamelogic
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+)
+
+// Territory says who controls a region. Owner is empty for a region
+// nobody has held yet.
+type Territory struct {
+	Location Location
+	Owner    string
+}
+
+// Victory is what it takes to win. Zero values switch a condition off;
+// with both off the game only ends when the server calls TimeUp.
+type Victory struct {
+	// Regions wins the game for the first player to control this many.
+	Regions int
+	// Elimination wins the game for the last player with units or
+	// regions, once at least two players have fielded units.
+	Elimination bool
+}
+
+// WithVictory sets the victory conditions. By default the game never
+// ends.
+func WithVictory(v Victory) WorldOption {
+	return func(w *World) {
+		w.victory = v
+	}
+}
+
+// settle works out the consequences of a change: who controls the regions
+// it touched, the accounts of everyone whose income that affects, and
+// whether somebody has won.
+func (w *World) settle(delta StateDelta) StateDelta {
+	var previousOwners []string
+	delta.Control, previousOwners = w.updateControl(delta.Wars)
+
+	touched := map[string]struct{}{}
+	for _, change := range delta.Players {
+		touched[change.Username] = struct{}{}
+	}
+	for _, t := range delta.Control {
+		touched[t.Owner] = struct{}{}
+	}
+	for _, name := range previousOwners {
+		touched[name] = struct{}{}
+	}
+	names := make([]string, 0, len(touched))
+	for name := range touched {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	incomes := w.incomes()
+	for _, name := range names {
+		delta.Accounts = append(delta.Accounts, w.account(name, incomes))
+	}
+
+	if w.over == nil {
+		w.over = w.checkVictory()
+		delta.GameOver = w.over
+	}
+	return delta
+}
+
+// updateControl hands regions to the winners of wars fought in them, and
+// to any player who is the only one with units in a region. Regions keep
+// their owner when the owner's units leave. It returns the regions that
+// changed hands and the players who held them before.
+func (w *World) updateControl(wars []WarResult) ([]Territory, []string) {
+	before := make(map[Location]string, len(w.control))
+	for loc, owner := range w.control {
+		before[loc] = owner
+	}
+
+	for _, war := range wars {
+		if !war.IsDraw() {
+			w.control[war.Location] = war.Winner
+		}
+	}
+	occupants := map[Location]map[string]struct{}{}
+	for name, p := range w.players {
+		for _, unit := range p.Units {
+			if occupants[unit.Location] == nil {
+				occupants[unit.Location] = map[string]struct{}{}
+			}
+			occupants[unit.Location][name] = struct{}{}
+		}
+		if len(p.Units) > 0 {
+			w.fielded[name] = struct{}{}
+		}
+	}
+	for loc, names := range occupants {
+		if len(names) != 1 {
+			continue
+		}
+		for name := range names {
+			w.control[loc] = name
+		}
+	}
+
+	changed := []Territory{}
+	previousOwners := []string{}
+	for loc, owner := range w.control {
+		if before[loc] == owner {
+			continue
+		}
+		changed = append(changed, Territory{Location: loc, Owner: owner})
+		if before[loc] != "" {
+			previousOwners = append(previousOwners, before[loc])
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool {
+		return changed[i].Location < changed[j].Location
+	})
+	return changed, previousOwners
+}
+
+func (w *World) territory() []Territory {
+	territory := make([]Territory, 0, len(w.gameMap.Regions))
+	for _, r := range w.gameMap.Regions {
+		territory = append(territory, Territory{Location: r.Name, Owner: w.control[r.Name]})
+	}
+	return territory
+}
+
+func (w *World) checkVictory() *routing.GameOver {
+	board := w.scoreboard()
+	if w.victory.Regions > 0 {
+		for _, s := range board {
+			if s.Regions >= w.victory.Regions {
+				return w.gameOver(s.Username, fmt.Sprintf("%s controls %d regions", s.Username, s.Regions), board)
+			}
+		}
+	}
+	if w.victory.Elimination && len(w.fielded) >= 2 {
+		standing := []string{}
+		for _, s := range board {
+			if _, ok := w.fielded[s.Username]; !ok || s.Units > 0 || s.Regions > 0 {
+				standing = append(standing, s.Username)
+			}
+		}
+		if len(standing) == 1 {
+			return w.gameOver(standing[0], fmt.Sprintf("%s has eliminated every opponent", standing[0]), board)
+		}
+	}
+	return nil
+}
+
+// TimeUp ends the game because its time limit has passed, won by the
+// highest score. It reports false if the game was already over.
+func (w *World) TimeUp() (routing.GameOver, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.over != nil {
+		return *w.over, false
+	}
+
+	board := w.scoreboard()
+	winner, reason := "", "time ran out with nobody playing"
+	switch {
+	case len(board) > 1 && board[0].Points == board[1].Points:
+		reason = fmt.Sprintf("time ran out with %s and %s tied", board[0].Username, board[1].Username)
+	case len(board) > 0:
+		winner = board[0].Username
+		reason = fmt.Sprintf("time ran out with %s ahead on points", winner)
+	}
+	w.over = w.gameOver(winner, reason, board)
+	return *w.over, true
+}
+
+// GameOver returns how the game ended, or nil while it is still going.
+func (w *World) GameOver() *routing.GameOver {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.over
+}
+
+func (w *World) gameOver(winner, reason string, board []routing.Score) *routing.GameOver {
+	return &routing.GameOver{
+		Winner:     winner,
+		Reason:     reason,
+		Scoreboard: board,
+	}
+}
+
+// scoreboard ranks the players by points: 10 for every region they
+// control, plus the power level of their units, plus their treasury.
+func (w *World) scoreboard() []routing.Score {
+	regions := map[string]int{}
+	for _, owner := range w.control {
+		regions[owner]++
+	}
+	board := make([]routing.Score, 0, len(w.players))
+	for name, p := range w.players {
+		units := make([]Unit, 0, len(p.Units))
+		for _, unit := range p.Units {
+			units = append(units, unit)
+		}
+		board = append(board, routing.Score{
+			Username: name,
+			Regions:  regions[name],
+			Units:    len(units),
+			Treasury: w.treasury[name],
+			Points:   10*regions[name] + unitsToPowerLevel(units) + w.treasury[name],
+		})
+	}
+	sort.Slice(board, func(i, j int) bool {
+		if board[i].Points != board[j].Points {
+			return board[i].Points > board[j].Points
+		}
+		return board[i].Username < board[j].Username
+	})
+	return board
+}
+
+func (w *World) checkOver() error {
+	if w.over == nil {
+		return nil
+	}
+	if w.over.Winner == "" {
+		return reject(RejectGameOver, "the game is over: %s", w.over.Reason)
+	}
+	return reject(RejectGameOver, "the game is over, %s won", w.over.Winner)
+}
+
+// HandleGameOver tells the player how the game ended. The client refuses
+// orders from then on.
+func (gs *GameState) HandleGameOver(over routing.GameOver) {
+	defer fmt.Println("------------------------")
+	fmt.Println()
+	gs.setGameOver(over)
+	fmt.Println("==== Game Over ====")
+	fmt.Println(over.Reason)
+	if over.Winner == gs.GetUsername() {
+		fmt.Println("You have won the game!")
+	}
+	for _, line := range over.ScoreboardLines() {
+		fmt.Println(line)
+	}
+}
+
+func (gs *GameState) setGameOver(over routing.GameOver) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.over = &over
+}
+
+func (gs *GameState) checkGameOver() error {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	if gs.over != nil {
+		return errors.New("the game is over")
+	}
+	return nil
+}
+
+func (gs *GameState) updateTerritory(territory []Territory) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	for _, t := range territory {
+		gs.territory[t.Location] = t.Owner
+	}
+}
+
+// ownedRegions lists the regions the player controls, in map order.
+func (gs *GameState) ownedRegions() []Location {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	owned := []Location{}
+	for _, r := range gs.gameMap.Regions {
+		if gs.territory[r.Name] == gs.Player.Username {
+			owned = append(owned, r.Name)
+		}
+	}
+	return owned
+}
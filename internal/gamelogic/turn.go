@@ -0,0 +1,264 @@
+package gamelogic
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+)
+
+// order is a command queued during a turn.
+type order struct {
+	username string
+	cmd      Command
+}
+
+// WithTurns makes the game turn-based: commands are queued with Queue
+// while a turn is open and carried out together by EndTurn.
+func WithTurns() WorldOption {
+	return func(w *World) {
+		w.turnBased = true
+	}
+}
+
+func (w *World) TurnBased() bool {
+	return w.turnBased
+}
+
+// Turn returns the current turn, or nil when the game is not turn-based.
+func (w *World) Turn() *routing.TurnState {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.currentTurn()
+}
+
+func (w *World) currentTurn() *routing.TurnState {
+	if !w.turnBased {
+		return nil
+	}
+	turn := w.turn
+	turn.Players = slices.Clone(w.turn.Players)
+	return &turn
+}
+
+// StartTurn opens the next turn, lasting length, to everyone who has
+// joined or played so far. Players who join during the turn wait for the
+// next one.
+func (w *World) StartTurn(length time.Duration) routing.TurnState {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	players := map[string]struct{}{}
+	for name := range w.players {
+		players[name] = struct{}{}
+	}
+	for name := range w.homes {
+		players[name] = struct{}{}
+	}
+	names := make([]string, 0, len(players))
+	for name := range players {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w.turn = routing.TurnState{
+		Turn:    w.turn.Turn + 1,
+		Started: true,
+		Ends:    time.Now().Add(length),
+		Players: names,
+	}
+	w.orders = nil
+	return *w.currentTurn()
+}
+
+// Queue checks a command against the state at the start of the turn and
+// keeps it for EndTurn. Commands the rules do not allow, or that come
+// from a player outside the current turn, fail with a *RejectedError.
+func (w *World) Queue(username string, cmd Command) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.checkOver(); err != nil {
+		return err
+	}
+	if w.paused {
+		return reject(RejectPaused, "the game is paused")
+	}
+	if !w.turn.Started {
+		return reject(RejectNotYourTurn, "no turn is in progress, wait for the next one")
+	}
+	if !slices.Contains(w.turn.Players, username) {
+		return reject(RejectNotYourTurn, "you joined during turn %d, wait for the next one", w.turn.Turn)
+	}
+
+	var err error
+	switch {
+	case cmd.Spawn != nil:
+		err = validateSpawn(w.gameMap, *cmd.Spawn)
+		if err == nil {
+			w.player(username)
+			err = w.checkFunds(username, cmd.Spawn.Rank, w.queuedSpending(username))
+		}
+	case cmd.Move != nil:
+		_, err = w.validateMove(username, *cmd.Move)
+	default:
+		err = reject(RejectInvalidCommand, "empty command")
+	}
+	if err != nil {
+		return err
+	}
+	w.orders = append(w.orders, order{username: username, cmd: cmd})
+	return nil
+}
+
+// EndTurn closes the turn and carries out its orders as if they all
+// happened at once. Spawns go first, then every move, and only then are
+// wars fought in the locations units moved into. A later order for a
+// unit replaces an earlier one. Who controls what, and whether anybody
+// has won, is settled once everything has happened, so the order in which
+// orders were queued makes no difference. Finally everyone collects their
+// income. The returned deltas are in the order they must be applied: one
+// per order, one settling the turn if anything changed and one for the
+// income. A turn that ends after the game is over carries out nothing.
+func (w *World) EndTurn() (routing.TurnState, []StateDelta) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.turn.Started = false
+	orders := w.orders
+	w.orders = nil
+	if w.over != nil {
+		return *w.currentTurn(), nil
+	}
+
+	deltas := []StateDelta{}
+	emit := func(delta StateDelta) {
+		w.seq++
+		delta.Seq = w.seq
+		deltas = append(deltas, delta)
+	}
+
+	incomes := w.incomes()
+	for _, o := range orders {
+		if o.cmd.Spawn == nil {
+			continue
+		}
+		delta, err := w.spawn(o.username, *o.cmd.Spawn)
+		if err == nil {
+			delta.Accounts = []Account{w.account(o.username, incomes)}
+			emit(delta)
+		}
+	}
+
+	// a unit follows the last order its player gave it
+	type unitKey struct {
+		username string
+		id       int
+	}
+	lastOrder := map[unitKey]int{}
+	for i, o := range orders {
+		if o.cmd.Move == nil {
+			continue
+		}
+		for _, id := range o.cmd.Move.UnitIDs {
+			lastOrder[unitKey{o.username, id}] = i
+		}
+	}
+	movers := map[Location][]string{}
+	for i, o := range orders {
+		if o.cmd.Move == nil {
+			continue
+		}
+		intent := MoveIntent{ToLocation: o.cmd.Move.ToLocation}
+		for _, id := range o.cmd.Move.UnitIDs {
+			if lastOrder[unitKey{o.username, id}] == i {
+				intent.UnitIDs = append(intent.UnitIDs, id)
+			}
+		}
+		if len(intent.UnitIDs) == 0 {
+			continue
+		}
+		// the state may have changed since the order was queued
+		moved, err := w.validateMove(o.username, intent)
+		if err != nil {
+			continue
+		}
+		emit(w.relocate(o.username, intent.ToLocation, moved))
+		if !slices.Contains(movers[intent.ToLocation], o.username) {
+			movers[intent.ToLocation] = append(movers[intent.ToLocation], o.username)
+		}
+	}
+
+	locations := make([]Location, 0, len(movers))
+	for loc := range movers {
+		locations = append(locations, loc)
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		return locations[i] < locations[j]
+	})
+	changes := map[string]*PlayerDelta{}
+	wars := []WarResult{}
+	for _, loc := range locations {
+		// two players who moved into the same place fight once, not twice
+		fought := map[string]struct{}{}
+		attackers := movers[loc]
+		sort.Strings(attackers)
+		for _, attacker := range attackers {
+			wars = append(wars, w.fight(attacker, loc, fought, changes)...)
+			fought[attacker] = struct{}{}
+		}
+	}
+	settled := w.settle(StateDelta{Players: sortedChanges(changes), Wars: wars})
+	if len(settled.Players) > 0 || len(settled.Wars) > 0 || len(settled.Control) > 0 || settled.GameOver != nil {
+		emit(settled)
+	}
+	if w.over == nil {
+		deltas = append(deltas, w.collectIncome())
+	}
+
+	return *w.currentTurn(), deltas
+}
+
+// HandleTurn keeps track of the turn the server announced.
+func (gs *GameState) HandleTurn(ts routing.TurnState) {
+	defer fmt.Println("------------------------")
+	fmt.Println()
+	gs.setTurn(ts)
+	if !ts.Started {
+		fmt.Printf("==== Turn %d Over ====\n", ts.Turn)
+		fmt.Println("Orders are being carried out.")
+		return
+	}
+	fmt.Printf("==== Turn %d ====\n", ts.Turn)
+	if !slices.Contains(ts.Players, gs.GetUsername()) {
+		fmt.Println("You can give orders from the next turn.")
+		return
+	}
+	fmt.Printf("Give your orders before %s.\n", ts.Ends.Format(time.TimeOnly))
+}
+
+func (gs *GameState) setTurn(ts routing.TurnState) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.turn = &ts
+}
+
+// checkTurn refuses orders outside the player's turn. Games that are not
+// turn-based have no turn and accept orders at any time.
+func (gs *GameState) checkTurn() error {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	if gs.turn == nil {
+		return nil
+	}
+	if !gs.turn.Started {
+		return errors.New("it is not your turn, wait for the next one")
+	}
+	if !slices.Contains(gs.turn.Players, gs.Player.Username) {
+		return errors.New("you joined during this turn, wait for the next one")
+	}
+	return nil
+}
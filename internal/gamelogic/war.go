@@ -2,90 +2,147 @@ package gamelogic
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 )
 
-type WarOutcome int
+// WarResult is the outcome of a battle between the attacker's and the
+// defender's units in one location. Winner and Loser are empty on a draw.
+type WarResult struct {
+	Attacker       string
+	Defender       string
+	Location       Location
+	Seed           int64
+	AttackerUnits  int
+	DefenderUnits  int
+	AttackerPower  int
+	DefenderPower  int
+	Winner         string
+	Loser          string
+	AttackerLosses []Unit
+	DefenderLosses []Unit
+}
 
-const (
-	WarOutcomeNotInvolved WarOutcome = iota
-	WarOutcomeNoUnits
-	WarOutcomeYouWon
-	WarOutcomeOpponentWon
-	WarOutcomeDraw
-)
+func (r WarResult) IsDraw() bool {
+	return r.Winner == ""
+}
 
-func (gs *GameState) HandleWar(rw RecognitionOfWar) (outcome WarOutcome, winner string, loser string) {
-	defer fmt.Println("------------------------")
-	fmt.Println()
-	fmt.Println("==== War Declared ====")
-	fmt.Printf("%s has declared war on %s!\n", rw.Attacker.Username, rw.Defender.Username)
+// Losses returns the units username lost in the war.
+func (r WarResult) Losses(username string) []Unit {
+	switch username {
+	case r.Attacker:
+		return r.AttackerLosses
+	case r.Defender:
+		return r.DefenderLosses
+	}
+	return nil
+}
 
-	player := gs.GetPlayerSnap()
+// CasualtyReport says how many units each side lost, by rank.
+func (r WarResult) CasualtyReport() string {
+	return fmt.Sprintf("%s lost %s; %s lost %s",
+		r.Attacker, describeLosses(r.AttackerLosses, r.AttackerUnits),
+		r.Defender, describeLosses(r.DefenderLosses, r.DefenderUnits),
+	)
+}
 
-	if player.Username == rw.Defender.Username {
-		fmt.Printf("%s, you published the war.\n", player.Username)
-		return WarOutcomeNotInvolved, "", ""
+func describeLosses(losses []Unit, units int) string {
+	if len(losses) == 0 {
+		return fmt.Sprintf("none of %d unit(s)", units)
 	}
-
-	if player.Username != rw.Attacker.Username {
-		fmt.Printf("%s, you are not involved in this war.\n", player.Username)
-		return WarOutcomeNotInvolved, "", ""
+	counts := map[UnitRank]int{}
+	for _, unit := range losses {
+		counts[unit.Rank]++
 	}
+	ranks := []string{}
+	for _, rank := range []UnitRank{RankInfantry, RankCavalry, RankArtillery} {
+		if counts[rank] > 0 {
+			ranks = append(ranks, fmt.Sprintf("%d %s", counts[rank], rank))
+		}
+	}
+	return fmt.Sprintf("%d of %d unit(s) (%s)", len(losses), units, strings.Join(ranks, ", "))
+}
 
+// ResolveWarWith fights out a war in the first location where both
+// players have units. It reports false if there is no such location.
+func ResolveWarWith(resolver CombatResolver, rw RecognitionOfWar) (WarResult, bool) {
 	overlappingLocation := getOverlappingLocation(rw.Attacker, rw.Defender)
 	if overlappingLocation == "" {
-		fmt.Printf("Error! No units are in the same location. No war will be fought.\n")
-		return WarOutcomeNoUnits, "", ""
+		return WarResult{}, false
 	}
 
-	attackerUnits := []Unit{}
-	defenderUnits := []Unit{}
-	for _, unit := range rw.Attacker.Units {
-		if unit.Location == overlappingLocation {
-			attackerUnits = append(attackerUnits, unit)
-		}
+	attackerUnits := unitsInLocation(rw.Attacker, overlappingLocation)
+	defenderUnits := unitsInLocation(rw.Defender, overlappingLocation)
+	attackerPower, defenderPower := resolver.Power(Battle{
+		Location: overlappingLocation,
+		Attacker: attackerUnits,
+		Defender: defenderUnits,
+		Seed:     rw.Seed,
+	})
+	result := WarResult{
+		Attacker:      rw.Attacker.Username,
+		Defender:      rw.Defender.Username,
+		Location:      overlappingLocation,
+		Seed:          rw.Seed,
+		AttackerUnits: len(attackerUnits),
+		DefenderUnits: len(defenderUnits),
+		AttackerPower: attackerPower,
+		DefenderPower: defenderPower,
 	}
-	for _, unit := range rw.Defender.Units {
-		if unit.Location == overlappingLocation {
-			defenderUnits = append(defenderUnits, unit)
-		}
+	switch {
+	case result.AttackerPower > result.DefenderPower:
+		result.Winner, result.Loser = result.Attacker, result.Defender
+		result.AttackerLosses, result.DefenderLosses = casualties(attackerUnits, defenderUnits, attackerPower, defenderPower)
+	case result.DefenderPower > result.AttackerPower:
+		result.Winner, result.Loser = result.Defender, result.Attacker
+		result.DefenderLosses, result.AttackerLosses = casualties(defenderUnits, attackerUnits, defenderPower, attackerPower)
+	default:
+		// a draw costs both sides half their units, rounded up
+		result.AttackerLosses = weakest(attackerUnits, (len(attackerUnits)+1)/2)
+		result.DefenderLosses = weakest(defenderUnits, (len(defenderUnits)+1)/2)
 	}
+	return result, true
+}
 
-	fmt.Printf("%s's units:\n", rw.Attacker.Username)
-	for _, unit := range attackerUnits {
-		fmt.Printf("  * %v\n", unit.Rank)
-	}
-	fmt.Printf("%s's units:\n", rw.Defender.Username)
-	for _, unit := range defenderUnits {
-		fmt.Printf("  * %v\n", unit.Rank)
-	}
-	attackerPower := unitsToPowerLevel(attackerUnits)
-	defenderPower := unitsToPowerLevel(defenderUnits)
-	fmt.Printf("Attacker has a power level of %v\n", attackerPower)
-	fmt.Printf("Defender has a power level of %v\n", defenderPower)
-	if attackerPower > defenderPower {
-		fmt.Printf("%s has won the war!\n", rw.Attacker.Username)
-		if player.Username == rw.Defender.Username {
-			fmt.Println("You have lost the war!")
-			gs.removeUnitsInLocation(overlappingLocation)
-			fmt.Printf("Your units in %s have been killed.\n", overlappingLocation)
-			return WarOutcomeOpponentWon, rw.Attacker.Username, rw.Defender.Username
+// casualties works out what the winner and the loser lose. The closer the
+// fight, the more it costs the winner: a narrow win loses half the
+// winner's units and half the loser's, while a rout loses the winner
+// nothing and the loser everything. Counts round in the loser's disfavour.
+func casualties(winner, loser []Unit, winnerPower, loserPower int) (winnerLosses, loserLosses []Unit) {
+	total := winnerPower + loserPower
+	diff := winnerPower - loserPower
+	// loser: (total+diff) / (2*total) of its units, rounded up
+	loserLost := (len(loser)*(total+diff) + 2*total - 1) / (2 * total)
+	// winner: loserPower / (2*winnerPower) of its units, rounded down
+	winnerLost := len(winner) * loserPower / (2 * winnerPower)
+	return weakest(winner, winnerLost), weakest(loser, loserLost)
+}
+
+// weakest picks the n weakest units, which are the first to fall. Units of
+// the same rank fall in ID order so every resolver agrees on who died.
+func weakest(units []Unit, n int) []Unit {
+	sorted := append([]Unit(nil), units...)
+	sort.Slice(sorted, func(i, j int) bool {
+		pi, pj := unitsToPowerLevel(sorted[i:i+1]), unitsToPowerLevel(sorted[j:j+1])
+		if pi != pj {
+			return pi < pj
 		}
-		return WarOutcomeYouWon, rw.Attacker.Username, rw.Defender.Username
-	} else if defenderPower > attackerPower {
-		fmt.Printf("%s has won the war!\n", rw.Defender.Username)
-		if player.Username == rw.Attacker.Username {
-			fmt.Println("You have lost the war!")
-			gs.removeUnitsInLocation(overlappingLocation)
-			fmt.Printf("Your units in %s have been killed.\n", overlappingLocation)
-			return WarOutcomeOpponentWon, rw.Defender.Username, rw.Attacker.Username
+		return sorted[i].ID < sorted[j].ID
+	})
+	if n > len(sorted) {
+		n = len(sorted)
+	}
+	return sorted[:n]
+}
+
+func unitsInLocation(p Player, loc Location) []Unit {
+	units := []Unit{}
+	for _, unit := range p.Units {
+		if unit.Location == loc {
+			units = append(units, unit)
 		}
-		return WarOutcomeYouWon, rw.Defender.Username, rw.Attacker.Username
 	}
-	fmt.Println("The war ended in a draw!")
-	fmt.Printf("Your units in %s have been killed.\n", overlappingLocation)
-	gs.removeUnitsInLocation(overlappingLocation)
-	return WarOutcomeDraw, rw.Attacker.Username, rw.Defender.Username
+	return units
 }
 
 func unitsToPowerLevel(units []Unit) int {
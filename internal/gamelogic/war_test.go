@@ -0,0 +1,171 @@
+package gamelogic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCasualties(t *testing.T) {
+	tests := []struct {
+		name        string
+		winner      []UnitRank
+		loser       []UnitRank
+		winnerPower int
+		loserPower  int
+		winnerLost  int
+		loserLost   int
+	}{
+		{
+			name:        "rout",
+			winner:      []UnitRank{RankArtillery},
+			loser:       []UnitRank{RankInfantry, RankInfantry},
+			winnerPower: 10,
+			loserPower:  0,
+			winnerLost:  0,
+			loserLost:   2,
+		},
+		{
+			name:        "clear win",
+			winner:      []UnitRank{RankArtillery, RankInfantry},
+			loser:       []UnitRank{RankInfantry},
+			winnerPower: 11,
+			loserPower:  1,
+			winnerLost:  0,
+			loserLost:   1,
+		},
+		{
+			name:        "narrow win",
+			winner:      []UnitRank{RankCavalry, RankInfantry, RankInfantry, RankInfantry},
+			loser:       []UnitRank{RankCavalry, RankInfantry, RankInfantry, RankInfantry},
+			winnerPower: 6,
+			loserPower:  5,
+			winnerLost:  1,
+			loserLost:   3,
+		},
+		{
+			name:        "narrowest win",
+			winner:      []UnitRank{RankInfantry, RankInfantry},
+			loser:       []UnitRank{RankInfantry, RankInfantry},
+			winnerPower: 100,
+			loserPower:  99,
+			winnerLost:  0,
+			loserLost:   2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			winnerLosses, loserLosses := casualties(testArmy("asia", tt.winner...), testArmy("asia", tt.loser...), tt.winnerPower, tt.loserPower)
+			if len(winnerLosses) != tt.winnerLost || len(loserLosses) != tt.loserLost {
+				t.Errorf("lost %d and %d, want %d and %d", len(winnerLosses), len(loserLosses), tt.winnerLost, tt.loserLost)
+			}
+		})
+	}
+}
+
+func TestWeakestFallFirst(t *testing.T) {
+	units := []Unit{
+		{ID: 1, Rank: RankArtillery},
+		{ID: 4, Rank: RankInfantry},
+		{ID: 2, Rank: RankCavalry},
+		{ID: 3, Rank: RankInfantry},
+	}
+	got := weakest(units, 3)
+	want := []Unit{
+		{ID: 3, Rank: RankInfantry},
+		{ID: 4, Rank: RankInfantry},
+		{ID: 2, Rank: RankCavalry},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("weakest = %v, want %v", got, want)
+	}
+	if got := weakest(units, 10); len(got) != len(units) {
+		t.Errorf("asking for more than there are: got %d units, want %d", len(got), len(units))
+	}
+}
+
+func TestResolveWarWith(t *testing.T) {
+	player := func(username string, ranks ...UnitRank) Player {
+		p := Player{Username: username, Units: map[int]Unit{}}
+		for _, unit := range testArmy("asia", ranks...) {
+			p.Units[unit.ID] = unit
+		}
+		// a unit at home stays out of the war
+		p.Units[99] = Unit{ID: 99, Rank: RankArtillery, Location: Location(username + "-home")}
+		return p
+	}
+	tests := []struct {
+		name           string
+		attacker       Player
+		defender       Player
+		winner         string
+		attackerLosses []int
+		defenderLosses []int
+		report         string
+	}{
+		{
+			name:           "attacker wins",
+			attacker:       player("alice", RankArtillery, RankInfantry),
+			defender:       player("bob", RankInfantry, RankInfantry),
+			winner:         "alice",
+			attackerLosses: []int{},
+			defenderLosses: []int{1, 2},
+			report:         "alice lost none of 2 unit(s); bob lost 2 of 2 unit(s) (2 infantry)",
+		},
+		{
+			name:           "defender wins",
+			attacker:       player("alice", RankInfantry),
+			defender:       player("bob", RankCavalry),
+			winner:         "bob",
+			attackerLosses: []int{1},
+			defenderLosses: []int{},
+			report:         "alice lost 1 of 1 unit(s) (1 infantry); bob lost none of 1 unit(s)",
+		},
+		{
+			name:           "draw",
+			attacker:       player("alice", RankCavalry, RankInfantry, RankInfantry, RankInfantry, RankInfantry, RankInfantry),
+			defender:       player("bob", RankArtillery),
+			attackerLosses: []int{2, 3, 4},
+			defenderLosses: []int{1},
+			report:         "alice lost 3 of 6 unit(s) (3 infantry); bob lost 1 of 1 unit(s) (1 artillery)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := ResolveWarWith(PowerLevelResolver{}, RecognitionOfWar{Attacker: tt.attacker, Defender: tt.defender, Seed: 7})
+			if !ok {
+				t.Fatal("no war was fought")
+			}
+			if result.Location != "asia" || result.Seed != 7 {
+				t.Errorf("fought in %s with seed %d, want asia with seed 7", result.Location, result.Seed)
+			}
+			if result.Winner != tt.winner {
+				t.Errorf("winner = %q, want %q", result.Winner, tt.winner)
+			}
+			if got := unitIDs(result.Losses("alice")); !reflect.DeepEqual(got, tt.attackerLosses) {
+				t.Errorf("alice lost %v, want %v", got, tt.attackerLosses)
+			}
+			if got := unitIDs(result.Losses("bob")); !reflect.DeepEqual(got, tt.defenderLosses) {
+				t.Errorf("bob lost %v, want %v", got, tt.defenderLosses)
+			}
+			if got := result.CasualtyReport(); got != tt.report {
+				t.Errorf("report = %q, want %q", got, tt.report)
+			}
+		})
+	}
+}
+
+func TestResolveWarWithoutContact(t *testing.T) {
+	alice := Player{Username: "alice", Units: map[int]Unit{1: {ID: 1, Rank: RankInfantry, Location: "asia"}}}
+	bob := Player{Username: "bob", Units: map[int]Unit{1: {ID: 1, Rank: RankInfantry, Location: "europe"}}}
+	if _, ok := ResolveWarWith(PowerLevelResolver{}, RecognitionOfWar{Attacker: alice, Defender: bob}); ok {
+		t.Error("a war was fought between players in different locations")
+	}
+}
+
+func unitIDs(units []Unit) []int {
+	ids := make([]int, len(units))
+	for i, unit := range units {
+		ids[i] = unit.ID
+	}
+	return ids
+}
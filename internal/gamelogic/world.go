@@ -0,0 +1,336 @@
+package gamelogic
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+)
+
+// Command is an intent a player sends to the server. Exactly one of the
+// fields is set.
+type Command struct {
+	Spawn *SpawnIntent
+	Move  *MoveIntent
+}
+
+// PlayerDelta lists the units of one player that appeared, moved or died.
+// Updates are applied before removals, so a unit that moved into a war and
+// died is in both.
+type PlayerDelta struct {
+	Username string
+	Updated  []Unit
+	Removed  []int
+}
+
+// StateDelta is what the server broadcasts after it has applied a command.
+// Move is set for moves, with only the mover's username in Move.Player.
+// Accounts holds the new state of every account the change touched.
+type StateDelta struct {
+	Seq      int
+	Players  []PlayerDelta
+	Move     *ArmyMove
+	Wars     []WarResult
+	Accounts []Account
+	// Control lists the regions that changed hands. GameOver is set on
+	// the delta that ended the game.
+	Control  []Territory
+	GameOver *routing.GameOver
+}
+
+// World is the server's authoritative copy of the game. Clients only send
+// it intents; it decides what actually happens, including wars, and
+// reports the result as a StateDelta.
+type World struct {
+	resolver  CombatResolver
+	gameMap   *Map
+	rng       *rand.Rand
+	turnBased bool
+	victory   Victory
+
+	mu       sync.Mutex
+	players  map[string]*Player
+	nextIDs  map[string]int
+	homes    map[string]Location
+	treasury map[string]int
+	paused   bool
+	seq      int
+	turn     routing.TurnState
+	orders   []order
+	control  map[Location]string
+	// fielded holds everyone who has ever had units, so a player who has
+	// not spawned yet does not count as eliminated
+	fielded map[string]struct{}
+	over    *routing.GameOver
+}
+
+type WorldOption func(*World)
+
+// WithCombatResolver replaces the DefaultResolver.
+func WithCombatResolver(r CombatResolver) WorldOption {
+	return func(w *World) {
+		w.resolver = r
+	}
+}
+
+// WithMap replaces the DefaultMap.
+func WithMap(m *Map) WorldOption {
+	return func(w *World) {
+		w.gameMap = m
+	}
+}
+
+// WithSeed seeds the dice the World hands to its wars, so that the same
+// commands always lead to the same battles.
+func WithSeed(seed int64) WorldOption {
+	return func(w *World) {
+		w.rng = rand.New(rand.NewSource(seed))
+	}
+}
+
+func NewWorld(opts ...WorldOption) *World {
+	w := &World{
+		resolver: DefaultResolver,
+		gameMap:  DefaultMap(),
+		rng:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		players:  map[string]*Player{},
+		nextIDs:  map[string]int{},
+		homes:    map[string]Location{},
+		treasury: map[string]int{},
+		control:  map[Location]string{},
+		fielded:  map[string]struct{}{},
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+func (w *World) SetPaused(paused bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.paused = paused
+}
+
+func (w *World) IsPaused() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.paused
+}
+
+// Apply validates a command from username and carries it out. Commands the
+// rules do not allow fail with a *RejectedError.
+func (w *World) Apply(username string, cmd Command) (StateDelta, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	err := w.checkOver()
+	if err != nil {
+		return StateDelta{}, err
+	}
+	var delta StateDelta
+	switch {
+	case cmd.Spawn != nil:
+		delta, err = w.spawn(username, *cmd.Spawn)
+	case cmd.Move != nil:
+		delta, err = w.move(username, *cmd.Move)
+	default:
+		err = reject(RejectInvalidCommand, "empty command")
+	}
+	if err != nil {
+		return StateDelta{}, err
+	}
+	w.seq++
+	delta.Seq = w.seq
+	return w.settle(delta), nil
+}
+
+func (w *World) spawn(username string, intent SpawnIntent) (StateDelta, error) {
+	err := validateSpawn(w.gameMap, intent)
+	if err != nil {
+		return StateDelta{}, err
+	}
+	p := w.player(username)
+	err = w.checkFunds(username, intent.Rank, 0)
+	if err != nil {
+		return StateDelta{}, err
+	}
+
+	w.treasury[username] -= w.gameMap.Costs[intent.Rank]
+	w.nextIDs[username]++
+	unit := Unit{
+		ID:       w.nextIDs[username],
+		Rank:     intent.Rank,
+		Location: intent.Location,
+	}
+	p.Units[unit.ID] = unit
+	return StateDelta{
+		Players: []PlayerDelta{{Username: username, Updated: []Unit{unit}}},
+	}, nil
+}
+
+func (w *World) move(username string, intent MoveIntent) (StateDelta, error) {
+	if w.paused {
+		return StateDelta{}, reject(RejectPaused, "the game is paused")
+	}
+	moved, err := w.validateMove(username, intent)
+	if err != nil {
+		return StateDelta{}, err
+	}
+	delta := w.relocate(username, intent.ToLocation, moved)
+	changes := map[string]*PlayerDelta{
+		username: &delta.Players[0],
+	}
+	delta.Wars = w.fight(username, intent.ToLocation, nil, changes)
+	delta.Players = sortedChanges(changes)
+	return delta, nil
+}
+
+// validateMove checks a move against the current state and returns the
+// units as they will be once moved.
+func (w *World) validateMove(username string, intent MoveIntent) ([]Unit, error) {
+	err := w.gameMap.validateLocation(intent.ToLocation)
+	if err != nil {
+		return nil, err
+	}
+	if len(intent.UnitIDs) == 0 {
+		return nil, reject(RejectInvalidCommand, "no units to move")
+	}
+
+	p := w.player(username)
+	moved := []Unit{}
+	for _, id := range intent.UnitIDs {
+		unit, ok := p.Units[id]
+		if !ok {
+			return nil, reject(RejectNotOwned, "you have no unit with ID %v", id)
+		}
+		err := w.gameMap.validatePath(unit, intent.ToLocation)
+		if err != nil {
+			return nil, err
+		}
+		unit.Location = intent.ToLocation
+		moved = append(moved, unit)
+	}
+	return moved, nil
+}
+
+// relocate puts validated units in their new location, without fighting
+// any wars the move starts.
+func (w *World) relocate(username string, to Location, moved []Unit) StateDelta {
+	p := w.player(username)
+	for _, unit := range moved {
+		p.Units[unit.ID] = unit
+	}
+	return StateDelta{
+		Players: []PlayerDelta{{Username: username, Updated: moved}},
+		Move: &ArmyMove{
+			Player:     Player{Username: username},
+			Units:      moved,
+			ToLocation: to,
+		},
+	}
+}
+
+// fight has attacker go to war with every other player in loc, except
+// those in spared, and records the losses in changes.
+func (w *World) fight(attacker string, loc Location, spared map[string]struct{}, changes map[string]*PlayerDelta) []WarResult {
+	var wars []WarResult
+	for _, defender := range w.playersIn(loc, attacker) {
+		if _, ok := spared[defender.Username]; ok {
+			continue
+		}
+		result, ok := ResolveWarWith(w.resolver, RecognitionOfWar{
+			Attacker: w.unitsAt(w.players[attacker], loc),
+			Defender: w.unitsAt(defender, loc),
+			Seed:     w.rng.Int63(),
+		})
+		if !ok {
+			continue
+		}
+		wars = append(wars, result)
+		for _, side := range []string{result.Attacker, result.Defender} {
+			losses := result.Losses(side)
+			if len(losses) == 0 {
+				continue
+			}
+			change, ok := changes[side]
+			if !ok {
+				change = &PlayerDelta{Username: side}
+				changes[side] = change
+			}
+			for _, unit := range losses {
+				delete(w.players[side].Units, unit.ID)
+				change.Removed = append(change.Removed, unit.ID)
+			}
+		}
+	}
+	return wars
+}
+
+func sortedChanges(changes map[string]*PlayerDelta) []PlayerDelta {
+	names := make([]string, 0, len(changes))
+	for name := range changes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	players := make([]PlayerDelta, 0, len(names))
+	for _, name := range names {
+		players = append(players, *changes[name])
+	}
+	return players
+}
+
+// player returns the named player, adding them to the game with the
+// map's starting treasury on first use.
+func (w *World) player(username string) *Player {
+	p, ok := w.players[username]
+	if !ok {
+		p = &Player{Username: username, Units: map[int]Unit{}}
+		w.players[username] = p
+		w.treasury[username] = w.gameMap.Treasury
+	}
+	return p
+}
+
+// playersIn lists the players other than except with units in loc, in a
+// stable order so wars are fought in the same sequence every time.
+func (w *World) playersIn(loc Location, except string) []*Player {
+	names := []string{}
+	for name, p := range w.players {
+		if name == except {
+			continue
+		}
+		for _, unit := range p.Units {
+			if unit.Location == loc {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	players := make([]*Player, len(names))
+	for i, name := range names {
+		players[i] = w.players[name]
+	}
+	return players
+}
+
+func (w *World) unitsAt(p *Player, loc Location) Player {
+	units := map[int]Unit{}
+	for id, unit := range p.Units {
+		if unit.Location == loc {
+			units[id] = unit
+		}
+	}
+	return Player{Username: p.Username, Units: units}
+}
+
+func (w *World) snapshot(p *Player) Player {
+	units := make(map[int]Unit, len(p.Units))
+	for id, unit := range p.Units {
+		units[id] = unit
+	}
+	return Player{Username: p.Username, Units: units}
+}
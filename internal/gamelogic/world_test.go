@@ -0,0 +1,371 @@
+package gamelogic
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+)
+
+// testMap is a line of three plains, west - middle - east, with a home at
+// either end.
+const testMap = `{
+  "name": "line",
+  "movement": {"infantry": 1, "cavalry": 1, "artillery": 1},
+  "costs": {"infantry": 1, "cavalry": 3, "artillery": 5},
+  "treasury": 10,
+  "regions": [
+    {"name": "west", "terrain": "plains", "yield": 1, "adjacent": ["middle"]},
+    {"name": "middle", "terrain": "plains", "yield": 2, "adjacent": ["east"]},
+    {"name": "east", "terrain": "plains", "yield": 3}
+  ],
+  "start": [
+    {"location": "west", "units": ["infantry"]},
+    {"location": "east", "units": ["infantry"]}
+  ]
+}`
+
+func newTestWorld(t *testing.T, opts ...WorldOption) *World {
+	t.Helper()
+	m, err := ParseMap([]byte(testMap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts = append([]WorldOption{WithMap(m), WithSeed(1)}, opts...)
+	w := NewWorld(opts...)
+	// alice starts in the west with unit 1, bob in the east with unit 1
+	w.Join("alice")
+	w.Join("bob")
+	return w
+}
+
+func spawnCmd(loc Location, rank UnitRank) Command {
+	return Command{Spawn: &SpawnIntent{Location: loc, Rank: rank}}
+}
+
+func moveCmd(to Location, ids ...int) Command {
+	return Command{Move: &MoveIntent{UnitIDs: ids, ToLocation: to}}
+}
+
+func mustApply(t *testing.T, w *World, username string, cmd Command) StateDelta {
+	t.Helper()
+	delta, err := w.Apply(username, cmd)
+	if err != nil {
+		t.Fatalf("%s: %v", username, err)
+	}
+	return delta
+}
+
+func TestSeedRepeatsWars(t *testing.T) {
+	play := func() []WarResult {
+		w := newTestWorld(t, WithCombatResolver(DiceResolver{}))
+		for i := 0; i < 3; i++ {
+			mustApply(t, w, "alice", spawnCmd("middle", RankCavalry))
+		}
+		mustApply(t, w, "bob", spawnCmd("middle", RankArtillery))
+		delta := mustApply(t, w, "bob", moveCmd("middle", 1))
+		return delta.Wars
+	}
+	first, second := play(), play()
+	if len(first) != 1 {
+		t.Fatalf("got %d wars, want 1", len(first))
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("the same seed fought different wars:\n%+v\n%+v", first, second)
+	}
+}
+
+func TestEndTurnOrder(t *testing.T) {
+	w := newTestWorld(t, WithTurns())
+	w.StartTurn(time.Minute)
+	// queued out of order: the spawn still comes first and the war only
+	// once both moves are done
+	for _, o := range []order{
+		{"alice", moveCmd("middle", 1)},
+		{"bob", moveCmd("middle", 1)},
+		{"alice", spawnCmd("west", RankCavalry)},
+	} {
+		if err := w.Queue(o.username, o.cmd); err != nil {
+			t.Fatalf("%s: %v", o.username, err)
+		}
+	}
+
+	_, deltas := w.EndTurn()
+	kinds := make([]string, len(deltas))
+	for i, d := range deltas {
+		switch {
+		case len(d.Wars) > 0:
+			kinds[i] = "war"
+		case d.Move != nil:
+			kinds[i] = "move " + d.Move.Player.Username
+		case len(d.Players) > 0:
+			kinds[i] = "spawn " + d.Players[0].Username
+		case len(d.Accounts) > 0 && d.Accounts[0].Collected > 0:
+			kinds[i] = "income"
+		}
+		if i > 0 && d.Seq != deltas[i-1].Seq+1 {
+			t.Errorf("delta %d has seq %d after %d", i, d.Seq, deltas[i-1].Seq)
+		}
+	}
+	want := []string{"spawn alice", "move alice", "move bob", "war", "income"}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("deltas = %v, want %v", kinds, want)
+	}
+
+	// infantry against infantry is a draw that costs both their unit, so
+	// nobody holds the middle and the income comes from the homes alone
+	income := deltas[len(deltas)-1]
+	wantAccounts := []Account{
+		{Username: "alice", Treasury: 10 - 3 + 1, Income: 1, Collected: 1},
+		{Username: "bob", Treasury: 10 + 3, Income: 3, Collected: 3},
+	}
+	if !reflect.DeepEqual(income.Accounts, wantAccounts) {
+		t.Errorf("income = %+v, want %+v", income.Accounts, wantAccounts)
+	}
+}
+
+func TestEndTurnIsSimultaneous(t *testing.T) {
+	tests := []struct {
+		name  string
+		first string
+	}{
+		{name: "alice queues first", first: "alice"},
+		{name: "bob queues first", first: "bob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// holding the middle as well as a home would win outright
+			w := newTestWorld(t, WithTurns(), WithVictory(Victory{Regions: 2}))
+			w.StartTurn(time.Minute)
+			second := "bob"
+			if tt.first == "bob" {
+				second = "alice"
+			}
+			for _, name := range []string{tt.first, second} {
+				if err := w.Queue(name, moveCmd("middle", 1)); err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+			}
+
+			_, deltas := w.EndTurn()
+			for i, d := range deltas[:len(deltas)-2] {
+				if len(d.Control) > 0 || d.GameOver != nil {
+					t.Errorf("delta %d settles control or victory before the turn is over: %+v", i, d)
+				}
+			}
+			settled := deltas[len(deltas)-2]
+			if len(settled.Wars) != 1 || !settled.Wars[0].IsDraw() {
+				t.Fatalf("wars = %+v, want one draw", settled.Wars)
+			}
+			if len(settled.Control) > 0 {
+				t.Errorf("control changed to %+v, want the middle left unheld", settled.Control)
+			}
+			if over := w.GameOver(); over != nil {
+				t.Errorf("game over: %s", over.Reason)
+			}
+		})
+	}
+}
+
+func TestEndTurnSettlesVictoryOnce(t *testing.T) {
+	w := newTestWorld(t, WithTurns(), WithVictory(Victory{Regions: 2}))
+	w.StartTurn(time.Minute)
+	w.Queue("alice", moveCmd("middle", 1))
+	w.Queue("bob", spawnCmd("east", RankInfantry))
+
+	_, deltas := w.EndTurn()
+	last := deltas[len(deltas)-1]
+	if last.GameOver == nil || last.GameOver.Winner != "alice" {
+		t.Fatalf("last delta = %+v, want alice to win", last)
+	}
+	want := []Territory{{Location: "middle", Owner: "alice"}}
+	if !reflect.DeepEqual(last.Control, want) {
+		t.Errorf("control = %+v, want %+v", last.Control, want)
+	}
+	// spawn, move and the settling delta; nobody is paid once it is over
+	if len(deltas) != 3 {
+		t.Errorf("got %d deltas, want 3", len(deltas))
+	}
+}
+
+func TestEndTurnAfterGameOver(t *testing.T) {
+	w := newTestWorld(t, WithTurns())
+	w.StartTurn(time.Minute)
+	w.Queue("alice", moveCmd("middle", 1))
+	w.TimeUp()
+
+	_, deltas := w.EndTurn()
+	if len(deltas) != 0 {
+		t.Errorf("got %d deltas after the game ended, want none", len(deltas))
+	}
+	if w.control["middle"] != "" {
+		t.Errorf("the middle went to %s after the game ended", w.control["middle"])
+	}
+}
+
+func TestEndTurnLastOrderWins(t *testing.T) {
+	w := newTestWorld(t, WithTurns())
+	w.StartTurn(time.Minute)
+	w.Queue("alice", moveCmd("middle", 1))
+	w.Queue("alice", moveCmd("west", 1))
+
+	_, deltas := w.EndTurn()
+	for _, d := range deltas {
+		if d.Move != nil && d.Move.ToLocation != "west" {
+			t.Errorf("unit moved to %s, want its last order, west", d.Move.ToLocation)
+		}
+	}
+}
+
+func assertRejected(t *testing.T, err error, want RejectReason) {
+	t.Helper()
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) || rejected.Reason != want {
+		t.Errorf("got %v, want a rejection for %s", err, want)
+	}
+}
+
+func TestSpawnCosts(t *testing.T) {
+	w := newTestWorld(t)
+	mustApply(t, w, "alice", spawnCmd("west", RankArtillery))
+	delta := mustApply(t, w, "alice", spawnCmd("west", RankArtillery))
+	want := []Account{{Username: "alice", Treasury: 0, Income: 1}}
+	if !reflect.DeepEqual(delta.Accounts, want) {
+		t.Errorf("accounts = %+v, want %+v", delta.Accounts, want)
+	}
+	_, err := w.Apply("alice", spawnCmd("west", RankInfantry))
+	assertRejected(t, err, RejectInsufficientFunds)
+}
+
+func TestCollectIncome(t *testing.T) {
+	w := newTestWorld(t)
+	// alice takes the middle, worth another 2 every payment
+	mustApply(t, w, "alice", moveCmd("middle", 1))
+
+	delta := w.CollectIncome()
+	want := []Account{
+		{Username: "alice", Treasury: 10 + 3, Income: 3, Collected: 3},
+		{Username: "bob", Treasury: 10 + 3, Income: 3, Collected: 3},
+	}
+	if !reflect.DeepEqual(delta.Accounts, want) {
+		t.Errorf("accounts = %+v, want %+v", delta.Accounts, want)
+	}
+}
+
+func TestQueuedSpending(t *testing.T) {
+	w := newTestWorld(t, WithTurns())
+	w.StartTurn(time.Minute)
+	// orders are paid for when the turn ends, but what they will cost is
+	// set aside as they are queued
+	for i := 0; i < 2; i++ {
+		if err := w.Queue("alice", spawnCmd("west", RankArtillery)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertRejected(t, w.Queue("alice", spawnCmd("west", RankInfantry)), RejectInsufficientFunds)
+}
+
+func TestScoreboard(t *testing.T) {
+	w := newTestWorld(t)
+	mustApply(t, w, "alice", spawnCmd("west", RankArtillery))
+	mustApply(t, w, "alice", moveCmd("middle", 1))
+	mustApply(t, w, "bob", spawnCmd("east", RankCavalry))
+
+	got := w.scoreboard()
+	want := []routing.Score{
+		// 2 regions, artillery and infantry, 10 - 5 left
+		{Username: "alice", Regions: 2, Units: 2, Treasury: 5, Points: 20 + 11 + 5},
+		// 1 region, cavalry and infantry, 10 - 3 left
+		{Username: "bob", Regions: 1, Units: 2, Treasury: 7, Points: 10 + 6 + 7},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scoreboard = %+v, want %+v", got, want)
+	}
+}
+
+func TestVictory(t *testing.T) {
+	tests := []struct {
+		name    string
+		victory Victory
+		play    func(t *testing.T, w *World) *routing.GameOver
+		winner  string
+	}{
+		{
+			name:    "regions",
+			victory: Victory{Regions: 2},
+			play: func(t *testing.T, w *World) *routing.GameOver {
+				if over := mustApply(t, w, "bob", spawnCmd("east", RankInfantry)).GameOver; over != nil {
+					t.Fatalf("game ended early: %s", over.Reason)
+				}
+				// alice keeps the west and takes the middle
+				return mustApply(t, w, "alice", moveCmd("middle", 1)).GameOver
+			},
+			winner: "alice",
+		},
+		{
+			name:    "elimination",
+			victory: Victory{Elimination: true},
+			play: func(t *testing.T, w *World) *routing.GameOver {
+				mustApply(t, w, "bob", spawnCmd("middle", RankArtillery))
+				// artillery against infantry is a rout that costs alice
+				// her only unit and, with it, her only region
+				return mustApply(t, w, "bob", moveCmd("west", 2)).GameOver
+			},
+			winner: "bob",
+		},
+		{
+			name: "time",
+			play: func(t *testing.T, w *World) *routing.GameOver {
+				mustApply(t, w, "bob", spawnCmd("east", RankCavalry))
+				over, ok := w.TimeUp()
+				if !ok {
+					t.Fatal("TimeUp reported the game as already over")
+				}
+				return &over
+			},
+			winner: "bob",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWorld(t, WithVictory(tt.victory))
+			over := tt.play(t, w)
+			if over == nil {
+				t.Fatal("the game did not end")
+			}
+			if over.Winner != tt.winner {
+				t.Errorf("winner = %q (%s), want %q", over.Winner, over.Reason, tt.winner)
+			}
+			if !reflect.DeepEqual(w.GameOver(), over) {
+				t.Errorf("GameOver() = %+v, want %+v", w.GameOver(), over)
+			}
+			_, err := w.Apply(tt.winner, spawnCmd("east", RankInfantry))
+			assertRejected(t, err, RejectGameOver)
+		})
+	}
+}
+
+func TestTimeUpTie(t *testing.T) {
+	w := newTestWorld(t)
+	// both have one region, one infantry and 10 in the treasury
+	over, ok := w.TimeUp()
+	if !ok {
+		t.Fatal("TimeUp reported the game as already over")
+	}
+	if over.Winner != "" {
+		t.Errorf("winner = %q, want nobody on a tie", over.Winner)
+	}
+	if _, ok := w.TimeUp(); ok {
+		t.Error("the game ended twice")
+	}
+}
+
+func TestNoVictoryByDefault(t *testing.T) {
+	w := newTestWorld(t)
+	mustApply(t, w, "bob", spawnCmd("middle", RankArtillery))
+	if over := mustApply(t, w, "bob", moveCmd("west", 2)).GameOver; over != nil {
+		t.Errorf("game ended without victory conditions: %s", over.Reason)
+	}
+}
@@ -0,0 +1,77 @@
+package perilpb
+
+import (
+	"fmt"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	"google.golang.org/protobuf/proto"
+)
+
+const ContentType = "application/protobuf"
+
+// Codec is a pubsub.Codec for the "application/protobuf" content type.
+// Besides generated messages it accepts the gamelogic and routing structs
+// that have a schema in peril.proto and converts them on the way through.
+type Codec struct{}
+
+func (Codec) ContentType() string {
+	return ContentType
+}
+
+func (Codec) Marshal(v any) ([]byte, error) {
+	m, err := toProto(v)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(m)
+}
+
+func (Codec) Unmarshal(data []byte, v any) error {
+	switch dst := v.(type) {
+	case proto.Message:
+		return proto.Unmarshal(data, dst)
+	case *gamelogic.Unit:
+		return unmarshalInto(data, &Unit{}, func(m *Unit) { *dst = m.ToGamelogic() })
+	case *gamelogic.Player:
+		return unmarshalInto(data, &Player{}, func(m *Player) { *dst = m.ToGamelogic() })
+	case *gamelogic.ArmyMove:
+		return unmarshalInto(data, &ArmyMove{}, func(m *ArmyMove) { *dst = m.ToGamelogic() })
+	case *gamelogic.RecognitionOfWar:
+		return unmarshalInto(data, &RecognitionOfWar{}, func(m *RecognitionOfWar) { *dst = m.ToGamelogic() })
+	case *routing.PlayingState:
+		return unmarshalInto(data, &PlayingState{}, func(m *PlayingState) { *dst = m.ToRouting() })
+	case *routing.GameLog:
+		return unmarshalInto(data, &GameLog{}, func(m *GameLog) { *dst = m.ToRouting() })
+	}
+	return fmt.Errorf("protobuf: no schema for %T", v)
+}
+
+func toProto(v any) (proto.Message, error) {
+	switch src := v.(type) {
+	case proto.Message:
+		return src, nil
+	case gamelogic.Unit:
+		return FromUnit(src), nil
+	case gamelogic.Player:
+		return FromPlayer(src), nil
+	case gamelogic.ArmyMove:
+		return FromArmyMove(src), nil
+	case gamelogic.RecognitionOfWar:
+		return FromRecognitionOfWar(src), nil
+	case routing.PlayingState:
+		return FromPlayingState(src), nil
+	case routing.GameLog:
+		return FromGameLog(src), nil
+	}
+	return nil, fmt.Errorf("protobuf: no schema for %T", v)
+}
+
+func unmarshalInto[M proto.Message](data []byte, m M, convert func(M)) error {
+	err := proto.Unmarshal(data, m)
+	if err != nil {
+		return err
+	}
+	convert(m)
+	return nil
+}
@@ -0,0 +1,156 @@
+package perilpb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	alice := gamelogic.Player{
+		Username: "alice",
+		Units: map[int]gamelogic.Unit{
+			1: {ID: 1, Rank: gamelogic.RankInfantry, Location: "europe"},
+			7: {ID: 7, Rank: gamelogic.RankArtillery, Location: "asia"},
+		},
+	}
+	bob := gamelogic.Player{
+		Username: "bob",
+		Units: map[int]gamelogic.Unit{
+			1 << 40: {ID: 1 << 40, Rank: gamelogic.RankCavalry, Location: "africa"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		in   any
+		out  any
+	}{
+		{"infantry", gamelogic.Unit{ID: 1, Rank: gamelogic.RankInfantry, Location: "europe"}, &gamelogic.Unit{}},
+		{"cavalry", gamelogic.Unit{ID: 2, Rank: gamelogic.RankCavalry, Location: "asia"}, &gamelogic.Unit{}},
+		{"artillery", gamelogic.Unit{ID: 3, Rank: gamelogic.RankArtillery, Location: "africa"}, &gamelogic.Unit{}},
+		{"player", alice, &gamelogic.Player{}},
+		{"player without units", gamelogic.Player{Username: "carol", Units: map[int]gamelogic.Unit{}}, &gamelogic.Player{}},
+		{"army move", gamelogic.ArmyMove{Player: alice, Units: []gamelogic.Unit{alice.Units[7]}, ToLocation: "americas"}, &gamelogic.ArmyMove{}},
+		{"recognition of war", gamelogic.RecognitionOfWar{Attacker: alice, Defender: bob, Seed: -42}, &gamelogic.RecognitionOfWar{}},
+		{"paused", routing.PlayingState{IsPaused: true}, &routing.PlayingState{}},
+		{"resumed", routing.PlayingState{IsPaused: false}, &routing.PlayingState{}},
+		{"game log", routing.GameLog{CurrentTime: time.Date(2024, 3, 1, 12, 30, 45, 123456789, time.UTC), Message: "alice won", Username: "alice"}, &routing.GameLog{}},
+		{"game log without a time", routing.GameLog{Message: "bob won", Username: "bob"}, &routing.GameLog{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Codec{}.Marshal(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := (Codec{}).Unmarshal(data, tt.out); err != nil {
+				t.Fatal(err)
+			}
+			got := reflect.ValueOf(tt.out).Elem().Interface()
+			if gl, ok := got.(routing.GameLog); ok {
+				// the time comes back in UTC, so compare instants
+				want := tt.in.(routing.GameLog)
+				if !gl.CurrentTime.Equal(want.CurrentTime) {
+					t.Errorf("time = %v, want %v", gl.CurrentTime, want.CurrentTime)
+				}
+				gl.CurrentTime, want.CurrentTime = time.Time{}, time.Time{}
+				got, tt.in = gl, want
+			}
+			if !reflect.DeepEqual(got, tt.in) {
+				t.Errorf("got %+v, want %+v", got, tt.in)
+			}
+		})
+	}
+}
+
+func TestUnitRank(t *testing.T) {
+	tests := []struct {
+		rank gamelogic.UnitRank
+		pb   UnitRank
+		back gamelogic.UnitRank
+	}{
+		{gamelogic.RankInfantry, UnitRank_UNIT_RANK_INFANTRY, gamelogic.RankInfantry},
+		{gamelogic.RankCavalry, UnitRank_UNIT_RANK_CAVALRY, gamelogic.RankCavalry},
+		{gamelogic.RankArtillery, UnitRank_UNIT_RANK_ARTILLERY, gamelogic.RankArtillery},
+		{"", UnitRank_UNIT_RANK_UNSPECIFIED, ""},
+		{"dragon", UnitRank_UNIT_RANK_UNSPECIFIED, ""},
+	}
+	for _, tt := range tests {
+		pb := FromUnitRank(tt.rank)
+		if pb != tt.pb {
+			t.Errorf("FromUnitRank(%q) = %v, want %v", tt.rank, pb, tt.pb)
+		}
+		if back := pb.ToGamelogic(); back != tt.back {
+			t.Errorf("%v.ToGamelogic() = %q, want %q", pb, back, tt.back)
+		}
+	}
+	if got := UnitRank(99).ToGamelogic(); got != "" {
+		t.Errorf("unknown wire rank decodes to %q, want no rank", got)
+	}
+}
+
+// Messages from other producers may leave out sub-messages; decoding them
+// must not panic and should give zero values.
+func TestCodecNilSubMessages(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  proto.Message
+		out  any
+		want any
+	}{
+		{
+			name: "army move without a player",
+			msg:  &ArmyMove{Units: []*Unit{nil, {Id: 2, Rank: UnitRank_UNIT_RANK_CAVALRY}}, ToLocation: "asia"},
+			out:  &gamelogic.ArmyMove{},
+			want: gamelogic.ArmyMove{
+				Player:     gamelogic.Player{Units: map[int]gamelogic.Unit{}},
+				Units:      []gamelogic.Unit{{}, {ID: 2, Rank: gamelogic.RankCavalry}},
+				ToLocation: "asia",
+			},
+		},
+		{
+			name: "war without a defender",
+			msg:  &RecognitionOfWar{Attacker: &Player{Username: "alice", Units: map[int64]*Unit{3: nil}}},
+			out:  &gamelogic.RecognitionOfWar{},
+			want: gamelogic.RecognitionOfWar{
+				Attacker: gamelogic.Player{Username: "alice", Units: map[int]gamelogic.Unit{3: {}}},
+				Defender: gamelogic.Player{Units: map[int]gamelogic.Unit{}},
+			},
+		},
+		{
+			name: "game log without a time",
+			msg:  &GameLog{Message: "hi", Username: "bob"},
+			out:  &routing.GameLog{},
+			want: routing.GameLog{Message: "hi", Username: "bob"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := proto.Marshal(tt.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := (Codec{}).Unmarshal(data, tt.out); err != nil {
+				t.Fatal(err)
+			}
+			got := reflect.ValueOf(tt.out).Elem().Interface()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCodecRejectsUnknownTypes(t *testing.T) {
+	if _, err := (Codec{}).Marshal(routing.TurnState{}); err == nil {
+		t.Error("marshalling a type without a schema succeeded")
+	}
+	if err := (Codec{}).Unmarshal(nil, &routing.TurnState{}); err == nil {
+		t.Error("unmarshalling into a type without a schema succeeded")
+	}
+}
@@ -0,0 +1,146 @@
+// Package perilpb holds the Protocol Buffers schema for every Peril
+// message, the generated types, and converters to the Go structs used by
+// gamelogic and routing.
+package perilpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative peril.proto
+
+import (
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func FromUnitRank(r gamelogic.UnitRank) UnitRank {
+	switch r {
+	case gamelogic.RankInfantry:
+		return UnitRank_UNIT_RANK_INFANTRY
+	case gamelogic.RankCavalry:
+		return UnitRank_UNIT_RANK_CAVALRY
+	case gamelogic.RankArtillery:
+		return UnitRank_UNIT_RANK_ARTILLERY
+	}
+	return UnitRank_UNIT_RANK_UNSPECIFIED
+}
+
+func (r UnitRank) ToGamelogic() gamelogic.UnitRank {
+	switch r {
+	case UnitRank_UNIT_RANK_INFANTRY:
+		return gamelogic.RankInfantry
+	case UnitRank_UNIT_RANK_CAVALRY:
+		return gamelogic.RankCavalry
+	case UnitRank_UNIT_RANK_ARTILLERY:
+		return gamelogic.RankArtillery
+	}
+	return ""
+}
+
+func FromUnit(u gamelogic.Unit) *Unit {
+	return &Unit{
+		Id:       int64(u.ID),
+		Rank:     FromUnitRank(u.Rank),
+		Location: string(u.Location),
+	}
+}
+
+func (x *Unit) ToGamelogic() gamelogic.Unit {
+	return gamelogic.Unit{
+		ID:       int(x.GetId()),
+		Rank:     x.GetRank().ToGamelogic(),
+		Location: gamelogic.Location(x.GetLocation()),
+	}
+}
+
+func FromPlayer(p gamelogic.Player) *Player {
+	units := make(map[int64]*Unit, len(p.Units))
+	for id, u := range p.Units {
+		units[int64(id)] = FromUnit(u)
+	}
+	return &Player{
+		Username: p.Username,
+		Units:    units,
+	}
+}
+
+func (x *Player) ToGamelogic() gamelogic.Player {
+	units := make(map[int]gamelogic.Unit, len(x.GetUnits()))
+	for id, u := range x.GetUnits() {
+		units[int(id)] = u.ToGamelogic()
+	}
+	return gamelogic.Player{
+		Username: x.GetUsername(),
+		Units:    units,
+	}
+}
+
+func FromArmyMove(mv gamelogic.ArmyMove) *ArmyMove {
+	units := make([]*Unit, 0, len(mv.Units))
+	for _, u := range mv.Units {
+		units = append(units, FromUnit(u))
+	}
+	return &ArmyMove{
+		Player:     FromPlayer(mv.Player),
+		Units:      units,
+		ToLocation: string(mv.ToLocation),
+	}
+}
+
+func (x *ArmyMove) ToGamelogic() gamelogic.ArmyMove {
+	units := make([]gamelogic.Unit, 0, len(x.GetUnits()))
+	for _, u := range x.GetUnits() {
+		units = append(units, u.ToGamelogic())
+	}
+	return gamelogic.ArmyMove{
+		Player:     x.GetPlayer().ToGamelogic(),
+		Units:      units,
+		ToLocation: gamelogic.Location(x.GetToLocation()),
+	}
+}
+
+func FromRecognitionOfWar(rw gamelogic.RecognitionOfWar) *RecognitionOfWar {
+	return &RecognitionOfWar{
+		Attacker: FromPlayer(rw.Attacker),
+		Defender: FromPlayer(rw.Defender),
+		Seed:     rw.Seed,
+	}
+}
+
+func (x *RecognitionOfWar) ToGamelogic() gamelogic.RecognitionOfWar {
+	return gamelogic.RecognitionOfWar{
+		Attacker: x.GetAttacker().ToGamelogic(),
+		Defender: x.GetDefender().ToGamelogic(),
+		Seed:     x.GetSeed(),
+	}
+}
+
+func FromPlayingState(ps routing.PlayingState) *PlayingState {
+	return &PlayingState{
+		IsPaused: ps.IsPaused,
+	}
+}
+
+func (x *PlayingState) ToRouting() routing.PlayingState {
+	return routing.PlayingState{
+		IsPaused: x.GetIsPaused(),
+	}
+}
+
+func FromGameLog(gl routing.GameLog) *GameLog {
+	return &GameLog{
+		CurrentTime: timestamppb.New(gl.CurrentTime),
+		Message:     gl.Message,
+		Username:    gl.Username,
+	}
+}
+
+func (x *GameLog) ToRouting() routing.GameLog {
+	gl := routing.GameLog{
+		Message:  x.GetMessage(),
+		Username: x.GetUsername(),
+	}
+	// AsTime would turn a missing timestamp into the Unix epoch
+	if x.GetCurrentTime() != nil {
+		gl.CurrentTime = x.GetCurrentTime().AsTime()
+	}
+	return gl
+}
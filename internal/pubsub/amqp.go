@@ -0,0 +1,267 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type AMQPBroker struct {
+	conn *amqp.Connection
+
+	mu        sync.Mutex
+	publishCh *amqp.Channel
+}
+
+func NewAMQPBroker(conn *amqp.Connection) (*AMQPBroker, error) {
+	publishCh, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("could not create channel: %v", err)
+	}
+	return &AMQPBroker{
+		conn:      conn,
+		publishCh: publishCh,
+	}, nil
+}
+
+func (b *AMQPBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.publishCh.Close()
+}
+
+func (b *AMQPBroker) Publish(ctx context.Context, exchange, key string, msg Message) error {
+	ch, err := b.publishChannel()
+	if err != nil {
+		return err
+	}
+	return ch.PublishWithContext(ctx, exchange, key, false, false, toPublishing(msg))
+}
+
+func (b *AMQPBroker) DeclareExchange(spec ExchangeSpec) error {
+	return b.withChannel(func(ch *amqp.Channel) error {
+		return ch.ExchangeDeclare(spec.Name, spec.Kind, spec.Durable, false, false, false, nil)
+	})
+}
+
+func (b *AMQPBroker) DeclareQueue(spec QueueSpec) (string, error) {
+	var name string
+	err := b.withChannel(func(ch *amqp.Channel) error {
+		queue, err := ch.QueueDeclare(spec.Name, spec.Durable, spec.AutoDelete, spec.Exclusive, false, toAMQPTable(spec.Args))
+		if err != nil {
+			return err
+		}
+		name = queue.Name
+		return nil
+	})
+	return name, err
+}
+
+func (b *AMQPBroker) BindQueue(queueName, key, exchange string) error {
+	return b.withChannel(func(ch *amqp.Channel) error {
+		return ch.QueueBind(queueName, key, exchange, false, nil)
+	})
+}
+
+func (b *AMQPBroker) ExchangeExists(name string) (bool, error) {
+	return b.exists(func(ch *amqp.Channel) error {
+		return ch.ExchangeDeclarePassive(name, "", false, false, false, false, nil)
+	})
+}
+
+func (b *AMQPBroker) QueueExists(name string) (bool, error) {
+	return b.exists(func(ch *amqp.Channel) error {
+		_, err := ch.QueueDeclarePassive(name, false, false, false, false, nil)
+		return err
+	})
+}
+
+// exists runs a passive declaration. The broker answers a missing entity
+// by closing the channel with 404, which is why each check gets its own.
+func (b *AMQPBroker) exists(passive func(ch *amqp.Channel) error) (bool, error) {
+	err := b.withChannel(passive)
+	var amqpErr *amqp.Error
+	if errors.As(err, &amqpErr) && amqpErr.Code == amqp.NotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (b *AMQPBroker) Consume(queueName string, prefetch int) (Consumer, error) {
+	ch, err := b.conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("could not create channel: %v", err)
+	}
+
+	err = ch.Qos(prefetch, 0, false)
+	if err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("could not set prefetch count: %v", err)
+	}
+
+	consumeChan, err := ch.Consume(queueName, "", false, false, false, false, nil)
+	if err != nil {
+		ch.Close()
+		return nil, err
+	}
+
+	c := &amqpConsumer{
+		ch:         ch,
+		deliveries: make(chan Delivery),
+		done:       make(chan struct{}),
+	}
+	go func() {
+		defer close(c.deliveries)
+		for d := range consumeChan {
+			select {
+			case c.deliveries <- fromAMQPDelivery(queueName, d):
+			case <-c.done:
+				return
+			}
+		}
+	}()
+
+	return c, nil
+}
+
+// publishChannel returns the shared publishing channel, replacing it if
+// the broker closed it after a channel-level error.
+func (b *AMQPBroker) publishChannel() (*amqp.Channel, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.publishCh.IsClosed() {
+		return b.publishCh, nil
+	}
+	ch, err := b.conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("could not create channel: %v", err)
+	}
+	b.publishCh = ch
+	return ch, nil
+}
+
+func (b *AMQPBroker) withChannel(fn func(ch *amqp.Channel) error) error {
+	ch, err := b.conn.Channel()
+	if err != nil {
+		return fmt.Errorf("could not create channel: %v", err)
+	}
+	defer ch.Close()
+	return fn(ch)
+}
+
+type amqpConsumer struct {
+	ch         *amqp.Channel
+	deliveries chan Delivery
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+func (c *amqpConsumer) Deliveries() <-chan Delivery {
+	return c.deliveries
+}
+
+func (c *amqpConsumer) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+	return c.ch.Close()
+}
+
+type amqpAcknowledger struct {
+	d amqp.Delivery
+}
+
+func (a amqpAcknowledger) Ack() error {
+	return a.d.Ack(false)
+}
+
+func (a amqpAcknowledger) Nack(requeue bool) error {
+	return a.d.Nack(false, requeue)
+}
+
+func toPublishing(msg Message) amqp.Publishing {
+	return amqp.Publishing{
+		ContentType:   msg.ContentType,
+		Body:          msg.Body,
+		MessageId:     msg.MessageID,
+		Timestamp:     msg.Timestamp,
+		CorrelationId: msg.CorrelationID,
+		ReplyTo:       msg.ReplyTo,
+		Headers:       toAMQPTable(msg.Headers),
+	}
+}
+
+func fromAMQPDelivery(queueName string, d amqp.Delivery) Delivery {
+	return Delivery{
+		Message: Message{
+			ContentType:   d.ContentType,
+			Body:          d.Body,
+			MessageID:     d.MessageId,
+			Timestamp:     d.Timestamp,
+			CorrelationID: d.CorrelationId,
+			ReplyTo:       d.ReplyTo,
+			Headers:       fromAMQPTable(d.Headers),
+		},
+		Queue:        queueName,
+		Exchange:     d.Exchange,
+		RoutingKey:   d.RoutingKey,
+		Redelivered:  d.Redelivered,
+		Acknowledger: amqpAcknowledger{d: d},
+	}
+}
+
+func toAMQPTable(t Table) amqp.Table {
+	if t == nil {
+		return nil
+	}
+	out := make(amqp.Table, len(t))
+	for k, v := range t {
+		out[k] = toAMQPValue(v)
+	}
+	return out
+}
+
+func toAMQPValue(v any) any {
+	switch v := v.(type) {
+	case Table:
+		return toAMQPTable(v)
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = toAMQPValue(item)
+		}
+		return out
+	}
+	return v
+}
+
+func fromAMQPTable(t amqp.Table) Table {
+	if t == nil {
+		return nil
+	}
+	out := make(Table, len(t))
+	for k, v := range t {
+		out[k] = fromAMQPValue(v)
+	}
+	return out
+}
+
+func fromAMQPValue(v any) any {
+	switch v := v.(type) {
+	case amqp.Table:
+		return fromAMQPTable(v)
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = fromAMQPValue(item)
+		}
+		return out
+	}
+	return v
+}
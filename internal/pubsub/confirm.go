@@ -0,0 +1,237 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var ErrNacked = errors.New("broker rejected message")
+
+type UnroutableError struct {
+	Exchange   string
+	RoutingKey string
+	ReplyCode  uint16
+	ReplyText  string
+}
+
+func (e *UnroutableError) Error() string {
+	return fmt.Sprintf("message to %s with key %s was returned: %d %s", e.Exchange, e.RoutingKey, e.ReplyCode, e.ReplyText)
+}
+
+// ConfirmingPublisher publishes with the mandatory flag on a channel in
+// confirm mode and only returns once the broker has taken responsibility
+// for the message, it has been returned as unroutable, or ctx is done.
+type ConfirmingPublisher struct {
+	connection func() (*amqp.Connection, error)
+
+	mu      sync.Mutex
+	conn    *amqp.Connection
+	ch      *amqp.Channel
+	tracker *confirmTracker
+}
+
+func NewConfirmingPublisher(conn *amqp.Connection) *ConfirmingPublisher {
+	return &ConfirmingPublisher{
+		connection: func() (*amqp.Connection, error) {
+			return conn, nil
+		},
+	}
+}
+
+func (m *ManagedConnection) ConfirmingPublisher() *ConfirmingPublisher {
+	return &ConfirmingPublisher{
+		connection: func() (*amqp.Connection, error) {
+			b := m.current()
+			if b == nil {
+				return nil, ErrDisconnected
+			}
+			return b.conn, nil
+		},
+	}
+}
+
+func (p *ConfirmingPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ch == nil {
+		return nil
+	}
+	return p.ch.Close()
+}
+
+func (p *ConfirmingPublisher) Publish(ctx context.Context, exchange, key string, msg Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ch, tracker, err := p.channel()
+	if err != nil {
+		return err
+	}
+
+	// returns are matched to their publish by message ID
+	if msg.MessageID == "" {
+		msg.MessageID = NewMessageID()
+	}
+	tag := ch.GetNextPublishSeqNo()
+	done, err := tracker.add(tag, msg.MessageID)
+	if err != nil {
+		return err
+	}
+	defer tracker.remove(tag)
+
+	err = ch.PublishWithContext(ctx, exchange, key, true, false, toPublishing(msg))
+	if err != nil {
+		return err
+	}
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for publisher confirm: %w", ctx.Err())
+	}
+}
+
+func (p *ConfirmingPublisher) channel() (*amqp.Channel, *confirmTracker, error) {
+	conn, err := p.connection()
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.ch != nil && p.conn == conn && !p.ch.IsClosed() {
+		return p.ch, p.tracker, nil
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create channel: %v", err)
+	}
+	err = ch.Confirm(false)
+	if err != nil {
+		ch.Close()
+		return nil, nil, fmt.Errorf("could not enable publisher confirms: %v", err)
+	}
+
+	// amqp091 delivers a message's basic.return before its ack from the
+	// same goroutine. With an unbuffered return channel and a single
+	// goroutine reading both, the return is always recorded first.
+	tracker := newConfirmTracker()
+	returns := ch.NotifyReturn(make(chan amqp.Return))
+	confirms := ch.NotifyPublish(make(chan amqp.Confirmation, 1))
+	go tracker.run(returns, confirms)
+
+	p.conn = conn
+	p.ch = ch
+	p.tracker = tracker
+	return ch, tracker, nil
+}
+
+// confirmTracker settles the publishes waiting on one channel. It keeps
+// draining returns and confirms even when nobody is waiting for them, so
+// a late return can neither block the connection nor be reported for a
+// later publish.
+type confirmTracker struct {
+	mu      sync.Mutex
+	pending map[uint64]*pendingConfirm
+	closed  bool
+}
+
+type pendingConfirm struct {
+	messageID string
+	returned  *amqp.Return
+	done      chan error
+}
+
+func newConfirmTracker() *confirmTracker {
+	return &confirmTracker{pending: map[uint64]*pendingConfirm{}}
+}
+
+// add registers the publish with delivery tag tag. The returned channel
+// receives its outcome once the broker confirms it.
+func (t *confirmTracker) add(tag uint64, messageID string) (<-chan error, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, ErrDisconnected
+	}
+	pc := &pendingConfirm{messageID: messageID, done: make(chan error, 1)}
+	t.pending[tag] = pc
+	return pc.done, nil
+}
+
+func (t *confirmTracker) remove(tag uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.pending, tag)
+}
+
+func (t *confirmTracker) run(returns <-chan amqp.Return, confirms <-chan amqp.Confirmation) {
+	for returns != nil || confirms != nil {
+		select {
+		case r, ok := <-returns:
+			if !ok {
+				returns = nil
+				continue
+			}
+			t.returned(r)
+		case c, ok := <-confirms:
+			if !ok {
+				confirms = nil
+				continue
+			}
+			t.confirmed(c)
+		}
+	}
+	t.close()
+}
+
+// returned records r against the publish it belongs to. Returns for
+// publishes nobody is waiting on any more are dropped.
+func (t *confirmTracker) returned(r amqp.Return) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, pc := range t.pending {
+		if pc.messageID == r.MessageId && pc.returned == nil {
+			pc.returned = &r
+			return
+		}
+	}
+}
+
+func (t *confirmTracker) confirmed(c amqp.Confirmation) {
+	t.mu.Lock()
+	pc, ok := t.pending[c.DeliveryTag]
+	delete(t.pending, c.DeliveryTag)
+	t.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	switch {
+	case pc.returned != nil:
+		pc.done <- &UnroutableError{
+			Exchange:   pc.returned.Exchange,
+			RoutingKey: pc.returned.RoutingKey,
+			ReplyCode:  pc.returned.ReplyCode,
+			ReplyText:  pc.returned.ReplyText,
+		}
+	case !c.Ack:
+		pc.done <- ErrNacked
+	default:
+		pc.done <- nil
+	}
+}
+
+// close fails every publish still waiting once the channel has gone.
+func (t *confirmTracker) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	for tag, pc := range t.pending {
+		pc.done <- ErrDisconnected
+		delete(t.pending, tag)
+	}
+}
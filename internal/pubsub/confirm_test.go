@@ -0,0 +1,82 @@
+package pubsub
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestConfirmTracker(t *testing.T) {
+	tracker := newConfirmTracker()
+	returns := make(chan amqp.Return)
+	confirms := make(chan amqp.Confirmation)
+	go tracker.run(returns, confirms)
+
+	// A return and ack for a publish that gave up on its confirm.
+	if _, err := tracker.add(1, "abandoned"); err != nil {
+		t.Fatal(err)
+	}
+	tracker.remove(1)
+	returns <- amqp.Return{MessageId: "abandoned", ReplyCode: 312}
+	confirms <- amqp.Confirmation{DeliveryTag: 1, Ack: true}
+
+	// Returns nobody waits for are drained instead of blocking the reader.
+	for i := 0; i < 3; i++ {
+		returns <- amqp.Return{MessageId: "stray"}
+	}
+
+	routed, err := tracker.add(2, "routed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirms <- amqp.Confirmation{DeliveryTag: 2, Ack: true}
+	if err := outcome(t, routed); err != nil {
+		t.Errorf("routed publish: got %v, want nil", err)
+	}
+
+	unroutable, err := tracker.add(3, "unroutable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	returns <- amqp.Return{MessageId: "unroutable", Exchange: "peril_direct", RoutingKey: "nowhere", ReplyCode: 312, ReplyText: "NO_ROUTE"}
+	confirms <- amqp.Confirmation{DeliveryTag: 3, Ack: true}
+	var unroutableErr *UnroutableError
+	if err := outcome(t, unroutable); !errors.As(err, &unroutableErr) || unroutableErr.RoutingKey != "nowhere" {
+		t.Errorf("unroutable publish: got %v, want an UnroutableError for key nowhere", err)
+	}
+
+	nacked, err := tracker.add(4, "nacked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirms <- amqp.Confirmation{DeliveryTag: 4, Ack: false}
+	if err := outcome(t, nacked); err != ErrNacked {
+		t.Errorf("nacked publish: got %v, want %v", err, ErrNacked)
+	}
+
+	waiting, err := tracker.add(5, "waiting")
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(returns)
+	close(confirms)
+	if err := outcome(t, waiting); err != ErrDisconnected {
+		t.Errorf("publish waiting when the channel closed: got %v, want %v", err, ErrDisconnected)
+	}
+	if _, err := tracker.add(6, "late"); err != ErrDisconnected {
+		t.Errorf("add after close: got %v, want %v", err, ErrDisconnected)
+	}
+}
+
+func outcome(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("publish was never settled")
+		return nil
+	}
+}
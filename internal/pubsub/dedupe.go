@@ -0,0 +1,209 @@
+package pubsub
+
+import (
+	"bufio"
+	"container/list"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DedupeStore remembers which messages a consumer has already finished
+// processing. Keys are opaque strings built from the queue name and the
+// message ID.
+type DedupeStore interface {
+	Seen(key string) bool
+	Mark(key string) error
+}
+
+// Dedupe makes sure each message ID is processed to completion at most
+// once per queue. Only acked messages are recorded: a message the handler
+// asked to requeue may be handled again, and so may one it discarded,
+// which comes back when it is replayed from the dead-letter queue after
+// whatever made it fail has been fixed.
+func Dedupe(store DedupeStore) Middleware {
+	return func(next Handler) Handler {
+		return func(d Delivery) Acktype {
+			if d.MessageID == "" {
+				return next(d)
+			}
+			key := d.Queue + "/" + d.MessageID
+			if store.Seen(key) {
+				return Ack
+			}
+
+			acktype := next(d)
+			if acktype == Ack {
+				err := store.Mark(key)
+				if err != nil {
+					fmt.Printf("could not record message %s as processed: %v\n", d.MessageID, err)
+				}
+			}
+			return acktype
+		}
+	}
+}
+
+type MemoryDedupeStore struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type dedupeEntry struct {
+	key    string
+	expiry time.Time
+}
+
+// NewMemoryDedupeStore keeps at most capacity keys, evicting the least
+// recently marked one first, and forgets keys after ttl.
+func NewMemoryDedupeStore(capacity int, ttl time.Duration) *MemoryDedupeStore {
+	return &MemoryDedupeStore{
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		entries:  map[string]*list.Element{},
+	}
+}
+
+func (s *MemoryDedupeStore) Seen(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[key]
+	if !ok {
+		return false
+	}
+	if time.Now().After(el.Value.(dedupeEntry).expiry) {
+		s.order.Remove(el)
+		delete(s.entries, key)
+		return false
+	}
+	return true
+}
+
+func (s *MemoryDedupeStore) Mark(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mark(key, time.Now().Add(s.ttl))
+	return nil
+}
+
+func (s *MemoryDedupeStore) mark(key string, expiry time.Time) {
+	if el, ok := s.entries[key]; ok {
+		el.Value = dedupeEntry{key: key, expiry: expiry}
+		s.order.MoveToFront(el)
+		return
+	}
+	s.entries[key] = s.order.PushFront(dedupeEntry{key: key, expiry: expiry})
+	for s.capacity > 0 && s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(dedupeEntry).key)
+	}
+}
+
+// FileDedupeStore persists processed keys to an append-only file so that
+// a restarted consumer still recognises messages it handled before.
+// Expired keys are dropped when the file is opened.
+type FileDedupeStore struct {
+	mem  *MemoryDedupeStore
+	mu   sync.Mutex
+	file *os.File
+}
+
+func OpenFileDedupeStore(path string, ttl time.Duration) (*FileDedupeStore, error) {
+	mem := NewMemoryDedupeStore(0, ttl)
+	err := loadDedupeFile(path, mem)
+	if err != nil {
+		return nil, err
+	}
+
+	// rewrite the file with only the keys that are still live
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open dedupe file: %v", err)
+	}
+	w := bufio.NewWriter(f)
+	for el := mem.order.Back(); el != nil; el = el.Prev() {
+		entry := el.Value.(dedupeEntry)
+		fmt.Fprintf(w, "%d %s\n", entry.expiry.UnixNano(), entry.key)
+	}
+	err = w.Flush()
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not write dedupe file: %v", err)
+	}
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return nil, fmt.Errorf("could not replace dedupe file: %v", err)
+	}
+
+	f, err = os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open dedupe file: %v", err)
+	}
+	return &FileDedupeStore{mem: mem, file: f}, nil
+}
+
+func (s *FileDedupeStore) Seen(key string) bool {
+	return s.mem.Seen(key)
+}
+
+func (s *FileDedupeStore) Mark(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiry := time.Now().Add(s.mem.ttl)
+	_, err := fmt.Fprintf(s.file, "%d %s\n", expiry.UnixNano(), key)
+	if err != nil {
+		return fmt.Errorf("could not write dedupe file: %v", err)
+	}
+	s.mem.mu.Lock()
+	s.mem.mark(key, expiry)
+	s.mem.mu.Unlock()
+	return nil
+}
+
+func (s *FileDedupeStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+func loadDedupeFile(path string, mem *MemoryDedupeStore) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not open dedupe file: %v", err)
+	}
+	defer f.Close()
+
+	now := time.Now()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		expiryStr, key, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			continue
+		}
+		nanos, err := strconv.ParseInt(expiryStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		expiry := time.Unix(0, nanos)
+		if expiry.After(now) {
+			mem.mark(key, expiry)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,168 @@
+package pubsub
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDedupe(t *testing.T) {
+	tests := []struct {
+		name      string
+		messageID string
+		acktype   Acktype
+		wantCalls int
+	}{
+		{name: "ack", messageID: "m1", acktype: Ack, wantCalls: 1},
+		{name: "requeue", messageID: "m1", acktype: NackRequeue, wantCalls: 2},
+		{name: "discard", messageID: "m1", acktype: NackDiscard, wantCalls: 2},
+		{name: "no message ID", acktype: Ack, wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			h := Dedupe(NewMemoryDedupeStore(10, time.Hour))(func(Delivery) Acktype {
+				calls++
+				return tt.acktype
+			})
+			d := Delivery{Message: Message{MessageID: tt.messageID}, Queue: "q"}
+			h(d)
+			got := h(d)
+			if calls != tt.wantCalls {
+				t.Errorf("handler ran %d times, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantCalls == 1 && got != Ack {
+				t.Errorf("duplicate got %v, want %v", got, Ack)
+			}
+		})
+	}
+}
+
+func TestDedupeKeysByQueue(t *testing.T) {
+	calls := 0
+	h := Dedupe(NewMemoryDedupeStore(10, time.Hour))(func(Delivery) Acktype {
+		calls++
+		return Ack
+	})
+	h(Delivery{Message: Message{MessageID: "m1"}, Queue: "a"})
+	h(Delivery{Message: Message{MessageID: "m1"}, Queue: "b"})
+	if calls != 2 {
+		t.Errorf("handler ran %d times for one message on two queues, want 2", calls)
+	}
+}
+
+func TestMemoryDedupeStore(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		marks    []string
+		seen     []string
+		unseen   []string
+	}{
+		{name: "unlimited", capacity: 0, marks: []string{"a", "b", "c"}, seen: []string{"a", "b", "c"}},
+		{name: "evicts the oldest", capacity: 2, marks: []string{"a", "b", "c"}, seen: []string{"b", "c"}, unseen: []string{"a"}},
+		{name: "marking again refreshes", capacity: 2, marks: []string{"a", "b", "a", "c"}, seen: []string{"a", "c"}, unseen: []string{"b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemoryDedupeStore(tt.capacity, time.Hour)
+			for _, key := range tt.marks {
+				s.Mark(key)
+			}
+			for _, key := range tt.seen {
+				if !s.Seen(key) {
+					t.Errorf("%s was forgotten", key)
+				}
+			}
+			for _, key := range tt.unseen {
+				if s.Seen(key) {
+					t.Errorf("%s was not evicted", key)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryDedupeStoreExpiry(t *testing.T) {
+	s := NewMemoryDedupeStore(0, time.Hour)
+	s.mark("old", time.Now().Add(-time.Second))
+	s.mark("new", time.Now().Add(time.Hour))
+	if s.Seen("old") {
+		t.Error("expired key is still seen")
+	}
+	if !s.Seen("new") {
+		t.Error("live key was forgotten")
+	}
+	if s.order.Len() != 1 {
+		t.Errorf("store holds %d keys, want the expired one dropped", s.order.Len())
+	}
+}
+
+func TestFileDedupeStoreReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedupe")
+	s, err := OpenFileDedupeStore(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"q/1", "q/2"} {
+		if err := s.Mark(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Close()
+
+	s, err = OpenFileDedupeStore(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for _, key := range []string{"q/1", "q/2"} {
+		if !s.Seen(key) {
+			t.Errorf("%s was forgotten across a restart", key)
+		}
+	}
+	if s.Seen("q/3") {
+		t.Error("q/3 was never marked but is seen")
+	}
+}
+
+func TestFileDedupeStoreCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedupe")
+	live := time.Now().Add(time.Hour).UnixNano()
+	expired := time.Now().Add(-time.Hour).UnixNano()
+	lines := []string{
+		fmt.Sprintf("%d q/live", live),
+		fmt.Sprintf("%d q/expired", expired),
+		"not a line",
+		"NaN q/garbage",
+		fmt.Sprintf("%d q/also-live", live),
+	}
+	err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := OpenFileDedupeStore(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if !s.Seen("q/live") || !s.Seen("q/also-live") {
+		t.Error("live keys were lost")
+	}
+	if s.Seen("q/expired") || s.Seen("q/garbage") {
+		t.Error("expired or malformed keys were kept")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("%d q/live\n%d q/also-live\n", live, live)
+	if string(data) != want {
+		t.Errorf("compacted file:\n%s\nwant:\n%s", data, want)
+	}
+}
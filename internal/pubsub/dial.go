@@ -0,0 +1,50 @@
+package pubsub
+
+import (
+	"crypto/tls"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type DialOption func(*amqp.Config)
+
+// WithVHost connects to vhost instead of the one named in the URL.
+func WithVHost(vhost string) DialOption {
+	return func(c *amqp.Config) {
+		c.Vhost = vhost
+	}
+}
+
+// WithTLS sets the TLS configuration used for amqps:// URLs.
+func WithTLS(cfg *tls.Config) DialOption {
+	return func(c *amqp.Config) {
+		c.TLSClientConfig = cfg
+	}
+}
+
+// WithPlainAuth logs in with a username and password instead of the ones
+// in the URL.
+func WithPlainAuth(username, password string) DialOption {
+	return func(c *amqp.Config) {
+		c.SASL = []amqp.Authentication{&amqp.PlainAuth{Username: username, Password: password}}
+	}
+}
+
+// WithExternalAuth logs in with the EXTERNAL mechanism, which RabbitMQ's
+// rabbitmq_auth_mechanism_ssl plugin resolves to the identity in the TLS
+// client certificate.
+func WithExternalAuth() DialOption {
+	return func(c *amqp.Config) {
+		c.SASL = []amqp.Authentication{&amqp.ExternalAuth{}}
+	}
+}
+
+func Dial(url string, opts ...DialOption) (*amqp.Connection, error) {
+	cfg := amqp.Config{
+		Locale: "en_US",
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return amqp.DialConfig(url, cfg)
+}
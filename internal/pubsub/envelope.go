@@ -0,0 +1,197 @@
+package pubsub
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+const DefaultSchemaVersion = 1
+
+const (
+	HeaderSender        = "x-sender"
+	HeaderSchemaVersion = "x-schema-version"
+	HeaderCausationID   = "x-causation-id"
+	HeaderDeadline      = "x-deadline"
+)
+
+// Envelope is the metadata the publish helpers attach to every message.
+// The message ID, timestamp, correlation ID and reply queue travel as AMQP
+// properties; the rest are carried in headers.
+type Envelope struct {
+	MessageID     string
+	PublishedAt   time.Time
+	Sender        string
+	SchemaVersion int
+	CorrelationID string
+	CausationID   string
+	ReplyTo       string
+	// Deadline is when the sender stops caring about the message; zero
+	// means never.
+	Deadline time.Time
+	// RoutingKey is the key a received message was published with. It is
+	// only set on the subscriber's side.
+	RoutingKey string
+
+	headers Table
+}
+
+type PublishOption func(*Envelope)
+
+// WithMessageID fixes the message ID, so that retries of the same logical
+// message can be recognised as duplicates by consumers.
+func WithMessageID(id string) PublishOption {
+	return func(e *Envelope) {
+		e.MessageID = id
+	}
+}
+
+func WithSender(username string) PublishOption {
+	return func(e *Envelope) {
+		e.Sender = username
+	}
+}
+
+func WithSchemaVersion(version int) PublishOption {
+	return func(e *Envelope) {
+		e.SchemaVersion = version
+	}
+}
+
+func WithCorrelationID(id string) PublishOption {
+	return func(e *Envelope) {
+		e.CorrelationID = id
+	}
+}
+
+// WithReplyTo names the queue the receiver should answer on.
+func WithReplyTo(queue string) PublishOption {
+	return func(e *Envelope) {
+		e.ReplyTo = queue
+	}
+}
+
+func WithDeadline(t time.Time) PublishOption {
+	return func(e *Envelope) {
+		e.Deadline = t
+	}
+}
+
+// withHeader sets a header outside the ones the envelope manages.
+func withHeader(key string, value any) PublishOption {
+	return func(e *Envelope) {
+		if e.headers == nil {
+			e.headers = Table{}
+		}
+		e.headers[key] = value
+	}
+}
+
+// CausedBy marks the message as a consequence of parent: it joins the
+// parent's correlation chain and records the parent as its cause.
+func CausedBy(parent Envelope) PublishOption {
+	return func(e *Envelope) {
+		e.CorrelationID = parent.CorrelationID
+		if e.CorrelationID == "" {
+			e.CorrelationID = parent.MessageID
+		}
+		e.CausationID = parent.MessageID
+	}
+}
+
+func newEnvelope(opts ...PublishOption) Envelope {
+	env := Envelope{
+		MessageID:     NewMessageID(),
+		PublishedAt:   time.Now().UTC(),
+		SchemaVersion: DefaultSchemaVersion,
+	}
+	for _, opt := range opts {
+		opt(&env)
+	}
+	if env.CorrelationID == "" {
+		env.CorrelationID = env.MessageID
+	}
+	return env
+}
+
+func (e Envelope) apply(msg *Message) {
+	msg.MessageID = e.MessageID
+	msg.Timestamp = e.PublishedAt
+	msg.CorrelationID = e.CorrelationID
+	msg.ReplyTo = e.ReplyTo
+	if msg.Headers == nil {
+		msg.Headers = Table{}
+	}
+	for k, v := range e.headers {
+		msg.Headers[k] = v
+	}
+	msg.Headers[HeaderSchemaVersion] = int32(e.SchemaVersion)
+	if e.Sender != "" {
+		msg.Headers[HeaderSender] = e.Sender
+	}
+	if e.CausationID != "" {
+		msg.Headers[HeaderCausationID] = e.CausationID
+	}
+	if !e.Deadline.IsZero() {
+		msg.Headers[HeaderDeadline] = e.Deadline.UnixMilli()
+	}
+}
+
+func EnvelopeOf(msg Message) Envelope {
+	env := Envelope{
+		MessageID:     msg.MessageID,
+		PublishedAt:   msg.Timestamp,
+		CorrelationID: msg.CorrelationID,
+		ReplyTo:       msg.ReplyTo,
+	}
+	env.Sender, _ = msg.Headers[HeaderSender].(string)
+	env.CausationID, _ = msg.Headers[HeaderCausationID].(string)
+	if v, ok := headerInt(msg.Headers[HeaderSchemaVersion]); ok {
+		env.SchemaVersion = v
+	}
+	if ms, ok := msg.Headers[HeaderDeadline].(int64); ok {
+		env.Deadline = time.UnixMilli(ms)
+	}
+	return env
+}
+
+// NewMessageID returns a random RFC 4122 version 4 UUID.
+func NewMessageID() string {
+	var b [16]byte
+	_, err := rand.Read(b[:])
+	if err != nil {
+		panic(err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:])
+	return string(buf)
+}
+
+func headerInt(v any) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int8:
+		return int(n), true
+	case int16:
+		return int(n), true
+	case int32:
+		return int(n), true
+	case int64:
+		return int(n), true
+	case uint8:
+		return int(n), true
+	}
+	return 0, false
+}
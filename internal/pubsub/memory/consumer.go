@@ -0,0 +1,159 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+)
+
+type consumer struct {
+	b        *Broker
+	q        *queue
+	prefetch int
+	unacked  map[*message]struct{}
+	pending  []pubsub.Delivery
+	cond     *sync.Cond
+	out      chan pubsub.Delivery
+	done     chan struct{}
+	closed   bool
+}
+
+func newConsumer(b *Broker, q *queue, prefetch int) *consumer {
+	return &consumer{
+		b:        b,
+		q:        q,
+		prefetch: prefetch,
+		unacked:  map[*message]struct{}{},
+		cond:     sync.NewCond(&b.mu),
+		out:      make(chan pubsub.Delivery),
+		done:     make(chan struct{}),
+	}
+}
+
+func (c *consumer) Deliveries() <-chan pubsub.Delivery {
+	return c.out
+}
+
+func (c *consumer) Close() error {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closeLocked()
+	c.q.removeConsumer(c)
+	if c.q.spec.AutoDelete && len(c.q.consumers) == 0 {
+		c.b.deleteQueue(c.q.spec.Name)
+		return nil
+	}
+	c.b.dispatch(c.q)
+	return nil
+}
+
+// closeLocked stops the consumer and requeues everything it has not
+// acknowledged yet. The caller must hold the broker lock and is
+// responsible for detaching the consumer from its queue.
+func (c *consumer) closeLocked() {
+	c.closed = true
+	close(c.done)
+	c.cond.Broadcast()
+
+	requeued := make([]*message, 0, len(c.unacked))
+	for m := range c.unacked {
+		m.redelivered = true
+		requeued = append(requeued, m)
+	}
+	sort.Slice(requeued, func(i, j int) bool {
+		return requeued[i].seq < requeued[j].seq
+	})
+	c.pending = nil
+	c.unacked = map[*message]struct{}{}
+	c.q.ready = append(requeued, c.q.ready...)
+}
+
+func (c *consumer) hasCapacity() bool {
+	return !c.closed && (c.prefetch <= 0 || len(c.unacked) < c.prefetch)
+}
+
+func (c *consumer) deliver(m *message) {
+	c.unacked[m] = struct{}{}
+	c.pending = append(c.pending, pubsub.Delivery{
+		Message:      m.msg,
+		Queue:        c.q.spec.Name,
+		Exchange:     m.exchange,
+		RoutingKey:   m.routingKey,
+		Redelivered:  m.redelivered,
+		Acknowledger: &acknowledger{c: c, m: m},
+	})
+	c.cond.Broadcast()
+}
+
+func (c *consumer) pump() {
+	defer close(c.out)
+	for {
+		c.b.mu.Lock()
+		for len(c.pending) == 0 && !c.closed {
+			c.cond.Wait()
+		}
+		if c.closed {
+			c.b.mu.Unlock()
+			return
+		}
+		d := c.pending[0]
+		c.pending = c.pending[1:]
+		c.b.mu.Unlock()
+
+		select {
+		case c.out <- d:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *consumer) settle(m *message) error {
+	if c.closed {
+		return fmt.Errorf("consumer closed: %w", ErrUnknownDelivery)
+	}
+	if _, ok := c.unacked[m]; !ok {
+		return ErrUnknownDelivery
+	}
+	delete(c.unacked, m)
+	return nil
+}
+
+type acknowledger struct {
+	c *consumer
+	m *message
+}
+
+func (a *acknowledger) Ack() error {
+	a.c.b.mu.Lock()
+	defer a.c.b.mu.Unlock()
+
+	if err := a.c.settle(a.m); err != nil {
+		return err
+	}
+	a.c.b.dispatch(a.c.q)
+	return nil
+}
+
+func (a *acknowledger) Nack(requeue bool) error {
+	a.c.b.mu.Lock()
+	defer a.c.b.mu.Unlock()
+
+	if err := a.c.settle(a.m); err != nil {
+		return err
+	}
+	if requeue {
+		a.m.redelivered = true
+		a.c.q.ready = append([]*message{a.m}, a.c.q.ready...)
+	} else {
+		a.c.b.deadLetter(a.c.q, a.m, "rejected")
+	}
+	a.c.b.dispatch(a.c.q)
+	return nil
+}
@@ -0,0 +1,383 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+)
+
+var (
+	ErrExchangeNotFound = errors.New("exchange not found")
+	ErrQueueNotFound    = errors.New("queue not found")
+	ErrPrecondition     = errors.New("inequivalent arguments")
+	ErrExclusiveQueue   = errors.New("queue is exclusively in use")
+	ErrUnknownDelivery  = errors.New("unknown delivery")
+)
+
+type Broker struct {
+	mu        sync.Mutex
+	exchanges map[string]*exchange
+	queues    map[string]*queue
+	nameSeq   int
+	msgSeq    uint64
+}
+
+type exchange struct {
+	spec     pubsub.ExchangeSpec
+	bindings []binding
+}
+
+type binding struct {
+	queue string
+	key   string
+}
+
+type message struct {
+	msg         pubsub.Message
+	exchange    string
+	routingKey  string
+	redelivered bool
+	seq         uint64
+}
+
+type queue struct {
+	spec        pubsub.QueueSpec
+	ready       []*message
+	consumers   []*consumer
+	next        int
+	hadConsumer bool
+}
+
+func New() *Broker {
+	return &Broker{
+		exchanges: map[string]*exchange{
+			"": {spec: pubsub.ExchangeSpec{Kind: pubsub.ExchangeDirect, Durable: true}},
+		},
+		queues: map[string]*queue{},
+	}
+}
+
+func (b *Broker) DeclareExchange(spec pubsub.ExchangeSpec) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch spec.Kind {
+	case pubsub.ExchangeDirect, pubsub.ExchangeTopic, pubsub.ExchangeFanout:
+	default:
+		return fmt.Errorf("exchange %s: unsupported kind %q", spec.Name, spec.Kind)
+	}
+
+	if ex, ok := b.exchanges[spec.Name]; ok {
+		if ex.spec != spec {
+			return fmt.Errorf("exchange %s: %w", spec.Name, ErrPrecondition)
+		}
+		return nil
+	}
+	b.exchanges[spec.Name] = &exchange{spec: spec}
+	return nil
+}
+
+func (b *Broker) DeclareQueue(spec pubsub.QueueSpec) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if spec.Name == "" {
+		b.nameSeq++
+		spec.Name = fmt.Sprintf("amq.gen-%d", b.nameSeq)
+	}
+
+	if q, ok := b.queues[spec.Name]; ok {
+		if !equivalentQueues(q.spec, spec) {
+			return "", fmt.Errorf("queue %s: %w", spec.Name, ErrPrecondition)
+		}
+		return spec.Name, nil
+	}
+	b.queues[spec.Name] = &queue{spec: spec}
+	b.exchanges[""].bindings = append(b.exchanges[""].bindings, binding{queue: spec.Name, key: spec.Name})
+	return spec.Name, nil
+}
+
+func (b *Broker) BindQueue(queueName, key, exchangeName string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ex, ok := b.exchanges[exchangeName]
+	if !ok || exchangeName == "" {
+		return fmt.Errorf("exchange %s: %w", exchangeName, ErrExchangeNotFound)
+	}
+	if _, ok := b.queues[queueName]; !ok {
+		return fmt.Errorf("queue %s: %w", queueName, ErrQueueNotFound)
+	}
+
+	bd := binding{queue: queueName, key: key}
+	for _, existing := range ex.bindings {
+		if existing == bd {
+			return nil
+		}
+	}
+	ex.bindings = append(ex.bindings, bd)
+	return nil
+}
+
+func (b *Broker) Publish(ctx context.Context, exchangeName, key string, msg pubsub.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.exchanges[exchangeName]; !ok {
+		return fmt.Errorf("exchange %s: %w", exchangeName, ErrExchangeNotFound)
+	}
+	b.route(exchangeName, key, msg)
+	return nil
+}
+
+func (b *Broker) Consume(queueName string, prefetch int) (pubsub.Consumer, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q, ok := b.queues[queueName]
+	if !ok {
+		return nil, fmt.Errorf("queue %s: %w", queueName, ErrQueueNotFound)
+	}
+	if q.spec.Exclusive && len(q.consumers) > 0 {
+		return nil, fmt.Errorf("queue %s: %w", queueName, ErrExclusiveQueue)
+	}
+
+	c := newConsumer(b, q, prefetch)
+	q.consumers = append(q.consumers, c)
+	q.hadConsumer = true
+	go c.pump()
+
+	b.dispatch(q)
+	return c, nil
+}
+
+func (b *Broker) ExchangeExists(name string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.exchanges[name]
+	return ok, nil
+}
+
+func (b *Broker) QueueExists(name string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.queues[name]
+	return ok, nil
+}
+
+// Restart simulates a broker restart: every consumer is disconnected,
+// unacknowledged messages are requeued, and only durable exchanges and
+// queues survive.
+func (b *Broker) Restart() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, q := range b.queues {
+		for _, c := range q.consumers {
+			c.closeLocked()
+		}
+		q.consumers = nil
+		q.next = 0
+	}
+
+	for name, q := range b.queues {
+		if !q.spec.Durable {
+			b.deleteQueue(name)
+		}
+	}
+	for name, ex := range b.exchanges {
+		if !ex.spec.Durable {
+			delete(b.exchanges, name)
+		}
+	}
+}
+
+// QueueLen reports the number of messages in a queue that are waiting
+// to be delivered, not counting unacknowledged ones.
+func (b *Broker) QueueLen(queueName string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q, ok := b.queues[queueName]
+	if !ok {
+		return 0
+	}
+	return len(q.ready)
+}
+
+func (b *Broker) route(exchangeName, key string, msg pubsub.Message) {
+	ex := b.exchanges[exchangeName]
+	routed := map[string]struct{}{}
+	for _, bd := range ex.bindings {
+		if _, ok := routed[bd.queue]; ok {
+			continue
+		}
+		if !matches(ex.spec.Kind, bd.key, key) {
+			continue
+		}
+		q, ok := b.queues[bd.queue]
+		if !ok {
+			continue
+		}
+		routed[bd.queue] = struct{}{}
+		b.msgSeq++
+		m := &message{
+			msg:        msg,
+			exchange:   exchangeName,
+			routingKey: key,
+			seq:        b.msgSeq,
+		}
+		q.ready = append(q.ready, m)
+		if ttl, ok := intArg(q.spec.Args["x-message-ttl"]); ok {
+			time.AfterFunc(time.Duration(ttl)*time.Millisecond, func() {
+				b.expire(q, m)
+			})
+		}
+		b.dispatch(q)
+	}
+}
+
+func (b *Broker) dispatch(q *queue) {
+	for len(q.ready) > 0 {
+		c := q.nextConsumer()
+		if c == nil {
+			return
+		}
+		m := q.ready[0]
+		q.ready = q.ready[1:]
+		c.deliver(m)
+	}
+}
+
+// expire dead-letters a message whose queue TTL ran out while it was still
+// waiting for a consumer.
+func (b *Broker) expire(q *queue, m *message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.queues[q.spec.Name] != q {
+		return
+	}
+	for i, ready := range q.ready {
+		if ready == m {
+			q.ready = append(q.ready[:i], q.ready[i+1:]...)
+			b.deadLetter(q, m, "expired")
+			return
+		}
+	}
+}
+
+func (b *Broker) deadLetter(q *queue, m *message, reason string) {
+	dlx, ok := q.spec.Args["x-dead-letter-exchange"].(string)
+	if !ok {
+		return
+	}
+	if _, ok := b.exchanges[dlx]; !ok {
+		return
+	}
+	key := m.routingKey
+	if dlk, ok := q.spec.Args["x-dead-letter-routing-key"].(string); ok {
+		key = dlk
+	}
+	msg := m.msg
+	msg.Headers = withDeath(msg.Headers, q.spec.Name, reason, m)
+	b.route(dlx, key, msg)
+}
+
+// withDeath returns a copy of headers with the x-death entry for queue and
+// reason added or incremented, the way RabbitMQ records dead-lettering.
+func withDeath(headers pubsub.Table, queueName, reason string, m *message) pubsub.Table {
+	out := make(pubsub.Table, len(headers)+1)
+	for k, v := range headers {
+		out[k] = v
+	}
+
+	deaths, _ := headers["x-death"].([]any)
+	updated := make([]any, 0, len(deaths)+1)
+	var count int64 = 1
+	for _, death := range deaths {
+		entry, ok := death.(pubsub.Table)
+		if ok && entry["queue"] == queueName && entry["reason"] == reason {
+			if n, ok := intArg(entry["count"]); ok {
+				count = n + 1
+			}
+			continue
+		}
+		updated = append(updated, death)
+	}
+	entry := pubsub.Table{
+		"queue":        queueName,
+		"reason":       reason,
+		"count":        count,
+		"exchange":     m.exchange,
+		"routing-keys": []any{m.routingKey},
+		"time":         time.Now(),
+	}
+	out["x-death"] = append([]any{entry}, updated...)
+	return out
+}
+
+func intArg(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	}
+	return 0, false
+}
+
+func (b *Broker) deleteQueue(name string) {
+	delete(b.queues, name)
+	for _, ex := range b.exchanges {
+		bindings := ex.bindings[:0]
+		for _, bd := range ex.bindings {
+			if bd.queue != name {
+				bindings = append(bindings, bd)
+			}
+		}
+		ex.bindings = bindings
+	}
+}
+
+func (q *queue) nextConsumer() *consumer {
+	for i := 0; i < len(q.consumers); i++ {
+		c := q.consumers[(q.next+i)%len(q.consumers)]
+		if c.hasCapacity() {
+			q.next = (q.next + i + 1) % len(q.consumers)
+			return c
+		}
+	}
+	return nil
+}
+
+func (q *queue) removeConsumer(c *consumer) {
+	for i, existing := range q.consumers {
+		if existing == c {
+			q.consumers = append(q.consumers[:i], q.consumers[i+1:]...)
+			break
+		}
+	}
+	q.next = 0
+}
+
+func equivalentQueues(a, b pubsub.QueueSpec) bool {
+	if a.Durable != b.Durable || a.AutoDelete != b.AutoDelete || a.Exclusive != b.Exclusive {
+		return false
+	}
+	if len(a.Args) == 0 && len(b.Args) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a.Args, b.Args)
+}
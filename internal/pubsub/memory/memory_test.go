@@ -0,0 +1,344 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+)
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		kind       string
+		bindingKey string
+		routingKey string
+		want       bool
+	}{
+		{pubsub.ExchangeDirect, "pause", "pause", true},
+		{pubsub.ExchangeDirect, "pause", "pause.x", false},
+		{pubsub.ExchangeFanout, "", "anything.at.all", true},
+		{pubsub.ExchangeTopic, "game_logs.*", "game_logs.alice", true},
+		{pubsub.ExchangeTopic, "game_logs.*", "game_logs", false},
+		{pubsub.ExchangeTopic, "game_logs.*", "game_logs.alice.bob", false},
+		{pubsub.ExchangeTopic, "army_moves.#", "army_moves", true},
+		{pubsub.ExchangeTopic, "army_moves.#", "army_moves.alice", true},
+		{pubsub.ExchangeTopic, "army_moves.#", "army_moves.alice.bob", true},
+		{pubsub.ExchangeTopic, "#", "", true},
+		{pubsub.ExchangeTopic, "#", "a.b.c", true},
+		{pubsub.ExchangeTopic, "#.war", "a.b.war", true},
+		{pubsub.ExchangeTopic, "#.war", "war", true},
+		{pubsub.ExchangeTopic, "#.war", "a.war.b", false},
+		{pubsub.ExchangeTopic, "a.#.c", "a.c", true},
+		{pubsub.ExchangeTopic, "a.#.c", "a.b.b.c", true},
+		{pubsub.ExchangeTopic, "*.*", "a.b", true},
+		{pubsub.ExchangeTopic, "*.*", "a", false},
+		{pubsub.ExchangeTopic, "state.alice", "state.bob", false},
+	}
+	for _, tt := range tests {
+		got := matches(tt.kind, tt.bindingKey, tt.routingKey)
+		if got != tt.want {
+			t.Errorf("matches(%s, %q, %q) = %v, want %v", tt.kind, tt.bindingKey, tt.routingKey, got, tt.want)
+		}
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	tests := []struct {
+		prefetch  int
+		published int
+		want      int
+	}{
+		{prefetch: 0, published: 5, want: 5},
+		{prefetch: 1, published: 5, want: 1},
+		{prefetch: 3, published: 5, want: 3},
+		{prefetch: 10, published: 5, want: 5},
+	}
+	for _, tt := range tests {
+		b := New()
+		declare(t, b, pubsub.QueueSpec{Name: "q"})
+		c, err := b.Consume("q", tt.prefetch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < tt.published; i++ {
+			publish(t, b, "", "q", nil)
+		}
+
+		var got []pubsub.Delivery
+		for len(got) < tt.published {
+			d, ok := receive(c, 20*time.Millisecond)
+			if !ok {
+				break
+			}
+			got = append(got, d)
+		}
+		if len(got) != tt.want {
+			t.Errorf("prefetch %d: got %d deliveries before acking, want %d", tt.prefetch, len(got), tt.want)
+		}
+
+		// Acking frees capacity for the rest.
+		for len(got) < tt.published {
+			if err := got[0].Ack(); err != nil {
+				t.Fatal(err)
+			}
+			got = got[1:]
+			d, ok := receive(c, time.Second)
+			if !ok {
+				t.Fatalf("prefetch %d: no delivery after ack", tt.prefetch)
+			}
+			got = append(got, d)
+			tt.published--
+		}
+		c.Close()
+	}
+}
+
+func TestSettle(t *testing.T) {
+	tests := []struct {
+		name            string
+		settle          func(pubsub.Delivery) error
+		wantRedelivered bool
+		wantDead        bool
+	}{
+		{
+			name:   "ack",
+			settle: pubsub.Delivery.Ack,
+		},
+		{
+			name:            "nack requeue",
+			settle:          func(d pubsub.Delivery) error { return d.Nack(true) },
+			wantRedelivered: true,
+		},
+		{
+			name:     "nack discard",
+			settle:   func(d pubsub.Delivery) error { return d.Nack(false) },
+			wantDead: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newDeadLetterBroker(t, nil)
+			c, err := b.Consume("work", 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			publish(t, b, "", "work", nil)
+
+			d, ok := receive(c, time.Second)
+			if !ok {
+				t.Fatal("no delivery")
+			}
+			if d.Redelivered {
+				t.Error("first delivery is marked redelivered")
+			}
+			if err := tt.settle(d); err != nil {
+				t.Fatal(err)
+			}
+			if err := d.Ack(); !tt.wantRedelivered && err != ErrUnknownDelivery {
+				t.Errorf("settling twice: got %v, want %v", err, ErrUnknownDelivery)
+			}
+
+			d, ok = receive(c, 20*time.Millisecond)
+			if ok != tt.wantRedelivered {
+				t.Fatalf("redelivered = %v, want %v", ok, tt.wantRedelivered)
+			}
+			if ok && !d.Redelivered {
+				t.Error("requeued delivery is not marked redelivered")
+			}
+
+			dead := 0
+			if tt.wantDead {
+				dead = 1
+			}
+			if got := b.QueueLen("dead"); got != dead {
+				t.Errorf("dead-letter queue holds %d messages, want %d", got, dead)
+			}
+			if tt.wantDead {
+				assertDeath(t, b, "work", "rejected", 1)
+			}
+		})
+	}
+}
+
+func TestTTLExpiry(t *testing.T) {
+	b := newDeadLetterBroker(t, pubsub.Table{"x-message-ttl": int32(10)})
+	publish(t, b, "", "work", nil)
+
+	deadline := time.Now().Add(time.Second)
+	for b.QueueLen("dead") == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("message did not expire")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := b.QueueLen("work"); got != 0 {
+		t.Errorf("work queue holds %d messages after expiry, want 0", got)
+	}
+	assertDeath(t, b, "work", "expired", 1)
+}
+
+func TestTTLSparesDeliveredMessages(t *testing.T) {
+	b := newDeadLetterBroker(t, pubsub.Table{"x-message-ttl": int32(10)})
+	c, err := b.Consume("work", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	publish(t, b, "", "work", nil)
+
+	d, ok := receive(c, time.Second)
+	if !ok {
+		t.Fatal("no delivery")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if err := d.Ack(); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.QueueLen("dead"); got != 0 {
+		t.Errorf("dead-letter queue holds %d messages, want 0", got)
+	}
+}
+
+func TestDeadLetterCountsDeaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous []any
+		want     int64
+		entries  int
+	}{
+		{name: "first death", want: 1, entries: 1},
+		{
+			name: "same queue and reason",
+			previous: []any{pubsub.Table{
+				"queue": "work", "reason": "rejected", "count": int64(2),
+			}},
+			want:    3,
+			entries: 1,
+		},
+		{
+			name: "other reason",
+			previous: []any{pubsub.Table{
+				"queue": "work", "reason": "expired", "count": int64(4),
+			}},
+			want:    1,
+			entries: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newDeadLetterBroker(t, pubsub.Table{"x-dead-letter-routing-key": "dead.key"})
+			if err := b.BindQueue("dead", "dead.key", "dlx"); err != nil {
+				t.Fatal(err)
+			}
+			c, err := b.Consume("work", 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			var headers pubsub.Table
+			if tt.previous != nil {
+				headers = pubsub.Table{"x-death": tt.previous}
+			}
+			publish(t, b, "", "work", headers)
+
+			d, ok := receive(c, time.Second)
+			if !ok {
+				t.Fatal("no delivery")
+			}
+			if err := d.Nack(false); err != nil {
+				t.Fatal(err)
+			}
+			deaths := assertDeath(t, b, "work", "rejected", tt.want)
+			if len(deaths) != tt.entries {
+				t.Errorf("x-death has %d entries, want %d", len(deaths), tt.entries)
+			}
+			death := deaths[0].(pubsub.Table)
+			if keys, _ := death["routing-keys"].([]any); len(keys) != 1 || keys[0] != "work" {
+				t.Errorf("routing-keys = %v, want [work]", death["routing-keys"])
+			}
+		})
+	}
+}
+
+func TestDeclareQueueRejectsInequivalentSpec(t *testing.T) {
+	b := New()
+	declare(t, b, pubsub.QueueSpec{Name: "q", Durable: true})
+	_, err := b.DeclareQueue(pubsub.QueueSpec{Name: "q", AutoDelete: true, Exclusive: true})
+	if err == nil || !strings.Contains(err.Error(), ErrPrecondition.Error()) {
+		t.Errorf("redeclaring with other flags: got %v, want %v", err, ErrPrecondition)
+	}
+}
+
+// newDeadLetterBroker returns a broker with a "work" queue that
+// dead-letters to the fanout exchange "dlx", which feeds the "dead" queue.
+func newDeadLetterBroker(t *testing.T, args pubsub.Table) *Broker {
+	t.Helper()
+	b := New()
+	if err := b.DeclareExchange(pubsub.ExchangeSpec{Name: "dlx", Kind: pubsub.ExchangeDirect}); err != nil {
+		t.Fatal(err)
+	}
+	workArgs := pubsub.Table{"x-dead-letter-exchange": "dlx"}
+	for k, v := range args {
+		workArgs[k] = v
+	}
+	declare(t, b, pubsub.QueueSpec{Name: "work", Args: workArgs})
+	declare(t, b, pubsub.QueueSpec{Name: "dead"})
+	if err := b.BindQueue("dead", "work", "dlx"); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func declare(t *testing.T, b *Broker, spec pubsub.QueueSpec) {
+	t.Helper()
+	if _, err := b.DeclareQueue(spec); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func publish(t *testing.T, b *Broker, exchange, key string, headers pubsub.Table) {
+	t.Helper()
+	err := b.Publish(context.Background(), exchange, key, pubsub.Message{Body: []byte("x"), Headers: headers})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func receive(c pubsub.Consumer, timeout time.Duration) (pubsub.Delivery, bool) {
+	select {
+	case d, ok := <-c.Deliveries():
+		return d, ok
+	case <-time.After(timeout):
+		return pubsub.Delivery{}, false
+	}
+}
+
+// assertDeath checks the newest x-death entry of the first dead-lettered
+// message and returns all of its entries.
+func assertDeath(t *testing.T, b *Broker, queueName, reason string, count int64) []any {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	dead := b.queues["dead"].ready
+	if len(dead) == 0 {
+		t.Fatal("nothing was dead-lettered")
+	}
+	deaths, _ := dead[0].msg.Headers["x-death"].([]any)
+	if len(deaths) == 0 {
+		t.Fatal("dead-lettered message has no x-death header")
+	}
+	death := deaths[0].(pubsub.Table)
+	if death["queue"] != queueName || death["reason"] != reason {
+		t.Errorf("x-death = %v/%v, want %s/%s", death["queue"], death["reason"], queueName, reason)
+	}
+	if death["count"] != count {
+		t.Errorf("x-death count = %v, want %d", death["count"], count)
+	}
+	if death["exchange"] != "" {
+		t.Errorf("x-death exchange = %q, want the default exchange", death["exchange"])
+	}
+	return deaths
+}
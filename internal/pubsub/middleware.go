@@ -0,0 +1,106 @@
+package pubsub
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+type Handler func(Delivery) Acktype
+
+// Middleware wraps a Handler with behaviour that applies to every
+// delivery of a subscription, regardless of the message type.
+type Middleware func(Handler) Handler
+
+// Chain composes middleware so that the first one is the outermost.
+func Chain(mws ...Middleware) Middleware {
+	return func(next Handler) Handler {
+		for i := len(mws) - 1; i >= 0; i-- {
+			next = mws[i](next)
+		}
+		return next
+	}
+}
+
+func (a Acktype) String() string {
+	switch a {
+	case Ack:
+		return "ack"
+	case NackRequeue:
+		return "nack (requeue)"
+	case NackDiscard:
+		return "nack (discard)"
+	}
+	return fmt.Sprintf("Acktype(%d)", int(a))
+}
+
+// Logging reports every delivery that was not acked.
+func Logging(logger *log.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(d Delivery) Acktype {
+			acktype := next(d)
+			if acktype != Ack {
+				logger.Printf("message %s from %s (%s): %v", d.MessageID, d.Queue, d.RoutingKey, acktype)
+			}
+			return acktype
+		}
+	}
+}
+
+// Recover turns a panicking handler into a discarded message instead of
+// a crashed process.
+func Recover(logger *log.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(d Delivery) (acktype Acktype) {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Printf("panic handling message %s from %s: %v\n%s", d.MessageID, d.Queue, r, debug.Stack())
+					acktype = NackDiscard
+				}
+			}()
+			return next(d)
+		}
+	}
+}
+
+func Timing(observe func(d Delivery, acktype Acktype, elapsed time.Duration)) Middleware {
+	return func(next Handler) Handler {
+		return func(d Delivery) Acktype {
+			start := time.Now()
+			acktype := next(d)
+			observe(d, acktype, time.Since(start))
+			return acktype
+		}
+	}
+}
+
+// RateLimit spaces out handler calls so that no more than n run per
+// period. Deliveries wait rather than being rejected. It panics unless n
+// and per are positive, like time.NewTicker does for a bad interval.
+func RateLimit(n int, per time.Duration) Middleware {
+	if n <= 0 || per <= 0 {
+		panic(fmt.Sprintf("pubsub: RateLimit needs a positive rate, got %d per %v", n, per))
+	}
+	interval := per / time.Duration(n)
+	var (
+		mu   sync.Mutex
+		next time.Time
+	)
+	return func(h Handler) Handler {
+		return func(d Delivery) Acktype {
+			mu.Lock()
+			now := time.Now()
+			if next.Before(now) {
+				next = now
+			}
+			wait := next.Sub(now)
+			next = next.Add(interval)
+			mu.Unlock()
+
+			time.Sleep(wait)
+			return h(d)
+		}
+	}
+}
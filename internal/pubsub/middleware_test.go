@@ -0,0 +1,44 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimitRejectsBadRates(t *testing.T) {
+	tests := []struct {
+		n   int
+		per time.Duration
+	}{
+		{n: 0, per: time.Second},
+		{n: -1, per: time.Second},
+		{n: 1, per: 0},
+		{n: 1, per: -time.Second},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RateLimit(%d, %v) did not panic", tt.n, tt.per)
+				}
+			}()
+			RateLimit(tt.n, tt.per)
+		}()
+	}
+}
+
+func TestRateLimitSpacesCalls(t *testing.T) {
+	const calls = 4
+	h := RateLimit(2, 40*time.Millisecond)(func(Delivery) Acktype { return Ack })
+
+	start := time.Now()
+	for i := 0; i < calls; i++ {
+		if got := h(Delivery{}); got != Ack {
+			t.Fatalf("call %d: got %v, want %v", i, got, Ack)
+		}
+	}
+	// The first call runs at once and each later one waits 20ms.
+	if elapsed, want := time.Since(start), 60*time.Millisecond; elapsed < want {
+		t.Errorf("%d calls took %v, want at least %v", calls, elapsed, want)
+	}
+}
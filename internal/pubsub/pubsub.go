@@ -1,14 +1,11 @@
 package pubsub
 
 import (
-	"bytes"
 	"context"
-	"encoding/gob"
-	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
-	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 type Acktype int
@@ -26,47 +23,118 @@ const (
 	NackDiscard
 )
 
-func PublishJSON[T any](ch *amqp.Channel, exchange, key string, val T) error {
-	jsonStr, err := json.Marshal(val)
+const (
+	ExchangeDirect = "direct"
+	ExchangeTopic  = "topic"
+	ExchangeFanout = "fanout"
+)
+
+type Table map[string]any
+
+type Message struct {
+	ContentType   string
+	Body          []byte
+	MessageID     string
+	Timestamp     time.Time
+	CorrelationID string
+	ReplyTo       string
+	Headers       Table
+}
+
+type Acknowledger interface {
+	Ack() error
+	Nack(requeue bool) error
+}
+
+type Delivery struct {
+	Message
+	Queue        string
+	Exchange     string
+	RoutingKey   string
+	Redelivered  bool
+	Acknowledger Acknowledger
+}
+
+func (d Delivery) Ack() error {
+	return d.Acknowledger.Ack()
+}
+
+func (d Delivery) Nack(requeue bool) error {
+	return d.Acknowledger.Nack(requeue)
+}
+
+type ExchangeSpec struct {
+	Name    string
+	Kind    string
+	Durable bool
+}
+
+type QueueSpec struct {
+	Name       string
+	Durable    bool
+	AutoDelete bool
+	Exclusive  bool
+	Args       Table
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, exchange, key string, msg Message) error
+}
+
+type Consumer interface {
+	Deliveries() <-chan Delivery
+	Close() error
+}
+
+type Subscriber interface {
+	Consume(queueName string, prefetch int) (Consumer, error)
+}
+
+type Topology interface {
+	DeclareExchange(spec ExchangeSpec) error
+	DeclareQueue(spec QueueSpec) (string, error)
+	BindQueue(queueName, key, exchange string) error
+}
+
+type Inspector interface {
+	ExchangeExists(name string) (bool, error)
+	QueueExists(name string) (bool, error)
+}
+
+type Broker interface {
+	Publisher
+	Subscriber
+	Topology
+}
+
+func Publish[T any](ctx context.Context, pub Publisher, codec Codec, exchange, key string, val T, opts ...PublishOption) error {
+	body, err := codec.Marshal(val)
 	if err != nil {
 		return err
 	}
-	ctx := context.Background()
-	msg := amqp.Publishing{
-		ContentType: "application/json",
-		Body:        jsonStr,
+	msg := Message{
+		ContentType: codec.ContentType(),
+		Body:        body,
 	}
-	return ch.PublishWithContext(ctx, exchange, key, false, false, msg)
+	newEnvelope(opts...).apply(&msg)
+	return pub.Publish(ctx, exchange, key, msg)
 }
 
-func PublishGob[T any](ch *amqp.Channel, exchange, key string, val T) error {
-	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
-	err := enc.Encode(val)
-	if err != nil {
-		return err
-	}
+func PublishJSON[T any](ctx context.Context, pub Publisher, exchange, key string, val T, opts ...PublishOption) error {
+	return Publish(ctx, pub, JSONCodec{}, exchange, key, val, opts...)
+}
 
-	ctx := context.Background()
-	msg := amqp.Publishing{
-		ContentType: "application/gob",
-		Body:        buf.Bytes(),
-	}
-	return ch.PublishWithContext(ctx, exchange, key, false, false, msg)
+func PublishGob[T any](ctx context.Context, pub Publisher, exchange, key string, val T, opts ...PublishOption) error {
+	return Publish(ctx, pub, GobCodec{}, exchange, key, val, opts...)
 }
 
 func DeclareAndBind(
-	conn *amqp.Connection,
+	t Topology,
 	exchange,
 	queueName,
 	key string,
 	simpleQueueType SimpleQueueType, // an enum to represent "durable" or "transient"
-) (*amqp.Channel, amqp.Queue, error) {
-	channel, err := conn.Channel()
-	if err != nil {
-		return nil, amqp.Queue{}, fmt.Errorf("could not create channel: %v", err)
-	}
-
+) (string, error) {
 	durable := simpleQueueType == DurableQueue
 
 	autoDelete, exclusive := false, false
@@ -74,109 +142,97 @@ func DeclareAndBind(
 		autoDelete, exclusive = true, true
 	}
 
-	table := amqp.Table{"x-dead-letter-exchange": routing.ExchangePerilDeadLetter}
-	queue, err := channel.QueueDeclare(queueName, durable, autoDelete, exclusive, false, table)
+	name, err := t.DeclareQueue(QueueSpec{
+		Name:       queueName,
+		Durable:    durable,
+		AutoDelete: autoDelete,
+		Exclusive:  exclusive,
+		Args:       Table{"x-dead-letter-exchange": routing.ExchangePerilDeadLetter},
+	})
 	if err != nil {
-		return nil, amqp.Queue{}, fmt.Errorf("could not declare queue: %v", err)
+		return "", fmt.Errorf("could not declare queue: %v", err)
 	}
 
-	err = channel.QueueBind(queueName, key, exchange, false, nil)
+	err = t.BindQueue(name, key, exchange)
 	if err != nil {
-		return nil, amqp.Queue{}, err
+		return "", err
 	}
 
-	return channel, queue, nil
+	return name, nil
 }
 
-func SubscribeJSON[T any](
-	conn *amqp.Connection,
+func Subscribe[T any](
+	ctx context.Context,
+	b Broker,
 	exchange,
 	queueName,
 	key string,
 	simpleQueueType SimpleQueueType,
 	handler func(T) Acktype,
-) error {
-	ch, queue, err := DeclareAndBind(conn, exchange, queueName, key, simpleQueueType)
-	if err != nil {
-		return err
-	}
-
-	err = ch.Qos(10, 0, false)
-	if err != nil {
-		return fmt.Errorf("could not set prefetch count: %v", err)
-	}
-
-	consumeChan, err := ch.Consume(queue.Name, "", false, false, false, false, nil)
-	if err != nil {
-		return fmt.Errorf("could not consume messages: %v", err)
-	}
-
-	go func() {
-		defer ch.Close()
-		for m := range consumeChan {
-			var data T
-			err := json.Unmarshal(m.Body, &data)
-			if err != nil {
-				fmt.Printf("could not unmarshal message: %v\n", err)
-				continue
-			}
-			acktype := handler(data)
-			switch acktype {
-			case Ack:
-				m.Ack(false)
-			case NackRequeue:
-				m.Nack(false, true)
-			case NackDiscard:
-				m.Nack(false, false)
-			}
-		}
-	}()
-	return nil
+	opts ...SubscribeOption,
+) (*Subscription, error) {
+	return SubscribeEnvelope(ctx, b, exchange, queueName, key, simpleQueueType, func(val T, _ Envelope) Acktype {
+		return handler(val)
+	}, opts...)
 }
 
-func SubscribeGob[T any](
-	conn *amqp.Connection,
+func SubscribeEnvelope[T any](
+	ctx context.Context,
+	b Broker,
 	exchange,
 	queueName,
 	key string,
 	simpleQueueType SimpleQueueType,
-	handler func(T) Acktype,
-) error {
-	ch, queue, err := DeclareAndBind(conn, exchange, queueName, key, simpleQueueType)
-	if err != nil {
-		return err
+	handler func(T, Envelope) Acktype,
+	opts ...SubscribeOption,
+) (*Subscription, error) {
+	cfg := subscribeConfig{prefetch: DefaultPrefetch}
+	for _, opt := range opts {
+		opt(&cfg)
 	}
 
-	err = ch.Qos(10, 0, false)
+	name, err := DeclareAndBind(b, exchange, queueName, key, simpleQueueType)
 	if err != nil {
-		return fmt.Errorf("could not set prefetch count: %v", err)
+		return nil, err
 	}
 
-	consumeChan, err := ch.Consume(queue.Name, "", false, false, false, false, nil)
+	if cfg.retry != nil {
+		err = cfg.retry.declare(b, name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	consumer, err := b.Consume(name, cfg.prefetch)
 	if err != nil {
-		return fmt.Errorf("could not consume messages: %v", err)
+		return nil, fmt.Errorf("could not consume messages: %v", err)
 	}
 
-	go func() {
-		defer ch.Close()
-		for msg := range consumeChan {
-			buf := bytes.NewBuffer(msg.Body)
-			dec := gob.NewDecoder(buf)
-			var data T
-			err := dec.Decode(&data)
-			if err != nil {
-				fmt.Printf("could not decode gob message: %v\n", err)
-			}
-			acktype := handler(data)
-			switch acktype {
-			case Ack:
-				msg.Ack(false)
-			case NackRequeue:
-				msg.Nack(false, true)
-			case NackDiscard:
-				msg.Nack(false, false)
-			}
+	var handle Handler = func(d Delivery) Acktype {
+		var data T
+		err := decode(d.Message, &data)
+		if err != nil {
+			fmt.Printf("could not decode message: %v\n", err)
+			return NackDiscard
 		}
-	}()
-	return nil
+		env := EnvelopeOf(d.Message)
+		env.RoutingKey = d.RoutingKey
+		return handler(data, env)
+	}
+	handle = Chain(cfg.middleware...)(handle)
+	if cfg.retry != nil {
+		handle = cfg.retry.middleware(b, name)(handle)
+	}
+
+	sub := newSubscription(ctx, consumer)
+	go sub.run(handle)
+	return sub, nil
+}
+
+func decode(msg Message, v any) error {
+	codec, err := CodecFor(msg.ContentType)
+	if err != nil {
+		return err
+	}
+	return codec.Unmarshal(msg.Body, v)
 }
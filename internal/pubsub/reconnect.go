@@ -0,0 +1,339 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var (
+	ErrDisconnected     = errors.New("not connected to the broker")
+	ErrConnectionClosed = errors.New("connection closed")
+)
+
+const (
+	minReconnectDelay = 500 * time.Millisecond
+	maxReconnectDelay = 30 * time.Second
+)
+
+type ReconnectHooks struct {
+	OnDisconnect   func(err error)
+	OnReconnecting func(attempt int, delay time.Duration)
+	OnReconnected  func()
+}
+
+// ManagedConnection is a Broker that survives connection loss. It redials
+// with exponential backoff, replays every exchange, queue and binding
+// declared through it, and resumes all consumers on the new connection.
+type ManagedConnection struct {
+	dial  func() (*amqp.Connection, error)
+	hooks ReconnectHooks
+
+	mu           sync.Mutex
+	conn         *amqp.Connection
+	broker       *AMQPBroker
+	changed      chan struct{}
+	declarations []declaration
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type declaration struct {
+	key   string
+	apply func(b *AMQPBroker) error
+}
+
+func DialManaged(url string, hooks ReconnectHooks, opts ...DialOption) (*ManagedConnection, error) {
+	m := &ManagedConnection{
+		dial: func() (*amqp.Connection, error) {
+			return Dial(url, opts...)
+		},
+		hooks:   hooks,
+		changed: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	conn, broker, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	m.setBroker(conn, broker)
+	go m.watch(conn)
+	return m, nil
+}
+
+func (m *ManagedConnection) Close() error {
+	var err error
+	m.closeOnce.Do(func() {
+		close(m.done)
+		m.mu.Lock()
+		conn := m.conn
+		m.mu.Unlock()
+		if conn != nil {
+			err = conn.Close()
+		}
+	})
+	return err
+}
+
+func (m *ManagedConnection) Publish(ctx context.Context, exchange, key string, msg Message) error {
+	b := m.current()
+	if b == nil {
+		return ErrDisconnected
+	}
+	return b.Publish(ctx, exchange, key, msg)
+}
+
+func (m *ManagedConnection) DeclareExchange(spec ExchangeSpec) error {
+	return m.declare(fmt.Sprintf("exchange %v", spec), func(b *AMQPBroker) error {
+		return b.DeclareExchange(spec)
+	})
+}
+
+func (m *ManagedConnection) DeclareQueue(spec QueueSpec) (string, error) {
+	var name string
+	err := m.declare(fmt.Sprintf("queue %v", spec), func(b *AMQPBroker) error {
+		var err error
+		name, err = b.DeclareQueue(spec)
+		return err
+	})
+	return name, err
+}
+
+func (m *ManagedConnection) BindQueue(queueName, key, exchange string) error {
+	return m.declare(fmt.Sprintf("binding %s %s %s", queueName, key, exchange), func(b *AMQPBroker) error {
+		return b.BindQueue(queueName, key, exchange)
+	})
+}
+
+func (m *ManagedConnection) ExchangeExists(name string) (bool, error) {
+	b := m.current()
+	if b == nil {
+		return false, ErrDisconnected
+	}
+	return b.ExchangeExists(name)
+}
+
+func (m *ManagedConnection) QueueExists(name string) (bool, error) {
+	b := m.current()
+	if b == nil {
+		return false, ErrDisconnected
+	}
+	return b.QueueExists(name)
+}
+
+func (m *ManagedConnection) Consume(queueName string, prefetch int) (Consumer, error) {
+	b := m.current()
+	if b == nil {
+		return nil, ErrDisconnected
+	}
+	inner, err := b.Consume(queueName, prefetch)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &managedConsumer{
+		deliveries: make(chan Delivery),
+		done:       make(chan struct{}),
+	}
+	go c.run(m, inner, queueName, prefetch)
+	return c, nil
+}
+
+func (m *ManagedConnection) declare(key string, apply func(b *AMQPBroker) error) error {
+	b := m.current()
+	if b == nil {
+		return ErrDisconnected
+	}
+	err := apply(b)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range m.declarations {
+		if d.key == key {
+			return nil
+		}
+	}
+	m.declarations = append(m.declarations, declaration{key: key, apply: apply})
+	return nil
+}
+
+func (m *ManagedConnection) current() *AMQPBroker {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.broker
+}
+
+func (m *ManagedConnection) awaitBroker(done <-chan struct{}) (*AMQPBroker, error) {
+	for {
+		m.mu.Lock()
+		b, changed := m.broker, m.changed
+		m.mu.Unlock()
+		if b != nil {
+			return b, nil
+		}
+
+		select {
+		case <-changed:
+		case <-done:
+			return nil, ErrConnectionClosed
+		case <-m.done:
+			return nil, ErrConnectionClosed
+		}
+	}
+}
+
+func (m *ManagedConnection) setBroker(conn *amqp.Connection, b *AMQPBroker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.conn = conn
+	m.broker = b
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+func (m *ManagedConnection) connect() (*amqp.Connection, *AMQPBroker, error) {
+	conn, err := m.dial()
+	if err != nil {
+		return nil, nil, err
+	}
+	broker, err := NewAMQPBroker(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	m.mu.Lock()
+	declarations := append([]declaration(nil), m.declarations...)
+	m.mu.Unlock()
+	for _, d := range declarations {
+		err := d.apply(broker)
+		if err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("could not restore %s: %v", d.key, err)
+		}
+	}
+	return conn, broker, nil
+}
+
+func (m *ManagedConnection) watch(conn *amqp.Connection) {
+	notify := conn.NotifyClose(make(chan *amqp.Error, 1))
+	for {
+		var closeErr *amqp.Error
+		select {
+		case closeErr = <-notify:
+		case <-m.done:
+			return
+		}
+		if closeErr == nil {
+			return
+		}
+
+		m.setBroker(nil, nil)
+		if m.hooks.OnDisconnect != nil {
+			m.hooks.OnDisconnect(closeErr)
+		}
+
+		var broker *AMQPBroker
+		conn, broker = m.redial()
+		if conn == nil {
+			return
+		}
+		notify = conn.NotifyClose(make(chan *amqp.Error, 1))
+		m.setBroker(conn, broker)
+		if m.hooks.OnReconnected != nil {
+			m.hooks.OnReconnected()
+		}
+	}
+}
+
+func (m *ManagedConnection) redial() (*amqp.Connection, *AMQPBroker) {
+	delay := minReconnectDelay
+	for attempt := 1; ; attempt++ {
+		if m.hooks.OnReconnecting != nil {
+			m.hooks.OnReconnecting(attempt, delay)
+		}
+		select {
+		case <-time.After(delay):
+		case <-m.done:
+			return nil, nil
+		}
+
+		conn, broker, err := m.connect()
+		if err == nil {
+			return conn, broker
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+type managedConsumer struct {
+	deliveries chan Delivery
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+func (c *managedConsumer) Deliveries() <-chan Delivery {
+	return c.deliveries
+}
+
+func (c *managedConsumer) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+	return nil
+}
+
+func (c *managedConsumer) run(m *ManagedConnection, inner Consumer, queueName string, prefetch int) {
+	defer close(c.deliveries)
+	for {
+		if !c.forward(inner) {
+			return
+		}
+
+		for {
+			b, err := m.awaitBroker(c.done)
+			if err != nil {
+				return
+			}
+			inner, err = b.Consume(queueName, prefetch)
+			if err == nil {
+				break
+			}
+			select {
+			case <-time.After(minReconnectDelay):
+			case <-c.done:
+				return
+			}
+		}
+	}
+}
+
+// forward relays deliveries until the inner consumer stops. It reports
+// whether the consumer should be resumed on a fresh channel.
+func (c *managedConsumer) forward(inner Consumer) bool {
+	defer inner.Close()
+	for {
+		select {
+		case d, ok := <-inner.Deliveries():
+			if !ok {
+				return true
+			}
+			select {
+			case c.deliveries <- d:
+			case <-c.done:
+				return false
+			}
+		case <-c.done:
+			return false
+		}
+	}
+}
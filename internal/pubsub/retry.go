@@ -0,0 +1,144 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	HeaderRetryAttempt       = "x-retry-attempt"
+	HeaderOriginalExchange   = "x-original-exchange"
+	HeaderOriginalRoutingKey = "x-original-routing-key"
+)
+
+// RetryPolicy replaces in-place requeueing with delayed redelivery. A
+// message whose handler asks for a requeue is republished to a retry queue
+// that holds it for the next delay and then dead-letters it back to the
+// original queue. Once MaxAttempts retries have been used up the message
+// is moved to the queue's parking lot instead.
+type RetryPolicy struct {
+	Delays      []time.Duration
+	MaxAttempts int
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	Delays:      []time.Duration{time.Second, 5 * time.Second, 30 * time.Second},
+	MaxAttempts: 5,
+}
+
+func WithRetry(policy RetryPolicy) SubscribeOption {
+	return func(c *subscribeConfig) {
+		c.retry = &policy
+	}
+}
+
+func RetryQueueName(queueName string, delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%dms", queueName, delay.Milliseconds())
+}
+
+func ParkingLotQueueName(queueName string) string {
+	return queueName + ".parking_lot"
+}
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	return p.Delays[min(attempt, len(p.Delays)-1)]
+}
+
+func (p RetryPolicy) declare(t Topology, queueName string) error {
+	if len(p.Delays) == 0 {
+		return fmt.Errorf("retry policy for %s has no delays", queueName)
+	}
+	for _, delay := range p.Delays {
+		_, err := t.DeclareQueue(QueueSpec{
+			Name:    RetryQueueName(queueName, delay),
+			Durable: true,
+			Args: Table{
+				"x-message-ttl":             delay.Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": queueName,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("could not declare retry queue: %v", err)
+		}
+	}
+	_, err := t.DeclareQueue(QueueSpec{
+		Name:    ParkingLotQueueName(queueName),
+		Durable: true,
+	})
+	if err != nil {
+		return fmt.Errorf("could not declare parking lot queue: %v", err)
+	}
+	return nil
+}
+
+func (p RetryPolicy) middleware(pub Publisher, queueName string) Middleware {
+	retryQueues := map[string]struct{}{}
+	for _, delay := range p.Delays {
+		retryQueues[RetryQueueName(queueName, delay)] = struct{}{}
+	}
+
+	return func(next Handler) Handler {
+		return func(d Delivery) Acktype {
+			acktype := next(d)
+			if acktype != NackRequeue {
+				return acktype
+			}
+
+			attempt := RetryAttempts(d.Message, retryQueues)
+			target := RetryQueueName(queueName, p.delay(attempt))
+			if attempt >= p.MaxAttempts {
+				target = ParkingLotQueueName(queueName)
+			}
+
+			err := pub.Publish(context.Background(), "", target, retryMessage(d, attempt+1))
+			if err != nil {
+				fmt.Printf("could not move message %s to %s: %v\n", d.MessageID, target, err)
+				return NackRequeue
+			}
+			return Ack
+		}
+	}
+}
+
+// RetryAttempts reports how many times a message has already come back
+// from one of the given retry queues. It trusts the broker's x-death
+// counts and falls back to the attempt header set when republishing.
+func RetryAttempts(msg Message, retryQueues map[string]struct{}) int {
+	attempts := 0
+	deaths, _ := msg.Headers["x-death"].([]any)
+	for _, death := range deaths {
+		entry, ok := death.(Table)
+		if !ok {
+			continue
+		}
+		queue, _ := entry["queue"].(string)
+		reason, _ := entry["reason"].(string)
+		if _, ok := retryQueues[queue]; !ok || reason != "expired" {
+			continue
+		}
+		if count, ok := headerInt(entry["count"]); ok {
+			attempts += count
+		}
+	}
+	if n, ok := headerInt(msg.Headers[HeaderRetryAttempt]); ok && n > attempts {
+		attempts = n
+	}
+	return attempts
+}
+
+func retryMessage(d Delivery, attempt int) Message {
+	msg := d.Message
+	headers := make(Table, len(msg.Headers)+3)
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	headers[HeaderRetryAttempt] = int32(attempt)
+	if _, ok := headers[HeaderOriginalExchange]; !ok {
+		headers[HeaderOriginalExchange] = d.Exchange
+		headers[HeaderOriginalRoutingKey] = d.RoutingKey
+	}
+	msg.Headers = headers
+	return msg
+}
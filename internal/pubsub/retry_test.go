@@ -0,0 +1,148 @@
+package pubsub_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub/memory"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := memory.New()
+	err := b.DeclareExchange(pubsub.ExchangeSpec{Name: "ex", Kind: pubsub.ExchangeTopic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := pubsub.RetryPolicy{
+		Delays:      []time.Duration{10 * time.Millisecond, 20 * time.Millisecond},
+		MaxAttempts: 3,
+	}
+	retryQueues := map[string]struct{}{}
+	for _, delay := range policy.Delays {
+		retryQueues[pubsub.RetryQueueName("work", delay)] = struct{}{}
+	}
+
+	// every delivery is recorded with the attempt x-death says it is on
+	// and the retry queue it last came back from
+	type attempt struct {
+		n    int
+		from string
+	}
+	var (
+		mu       sync.Mutex
+		attempts []attempt
+	)
+	observe := func(next pubsub.Handler) pubsub.Handler {
+		return func(d pubsub.Delivery) pubsub.Acktype {
+			a := attempt{n: pubsub.RetryAttempts(pubsub.Message{Headers: pubsub.Table{"x-death": d.Headers["x-death"]}}, retryQueues)}
+			if deaths, _ := d.Headers["x-death"].([]any); len(deaths) > 0 {
+				a.from, _ = deaths[0].(pubsub.Table)["queue"].(string)
+			}
+			mu.Lock()
+			attempts = append(attempts, a)
+			mu.Unlock()
+			return next(d)
+		}
+	}
+
+	sub, err := pubsub.Subscribe(ctx, b, "ex", "work", "work.*", pubsub.DurableQueue,
+		func(struct{}) pubsub.Acktype { return pubsub.NackRequeue },
+		pubsub.WithRetry(policy),
+		pubsub.WithMiddleware(observe),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	err = pubsub.PublishJSON(ctx, b, "ex", "work.alice", struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parkingLot := pubsub.ParkingLotQueueName("work")
+	deadline := time.Now().Add(5 * time.Second)
+	for b.QueueLen(parkingLot) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the message never reached the parking lot")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []attempt{
+		{n: 0},
+		{n: 1, from: pubsub.RetryQueueName("work", 10*time.Millisecond)},
+		{n: 2, from: pubsub.RetryQueueName("work", 20*time.Millisecond)},
+		// the last delay is reused once the list runs out
+		{n: 3, from: pubsub.RetryQueueName("work", 20*time.Millisecond)},
+	}
+	if !reflect.DeepEqual(attempts, want) {
+		t.Errorf("deliveries = %+v, want %+v", attempts, want)
+	}
+	for queue := range retryQueues {
+		if n := b.QueueLen(queue); n != 0 {
+			t.Errorf("%s still holds %d messages", queue, n)
+		}
+	}
+}
+
+func TestRetryAttempts(t *testing.T) {
+	retryQueues := map[string]struct{}{"work.retry.1000ms": {}, "work.retry.5000ms": {}}
+	death := func(queue, reason string, count int64) pubsub.Table {
+		return pubsub.Table{"queue": queue, "reason": reason, "count": count}
+	}
+	tests := []struct {
+		name    string
+		headers pubsub.Table
+		want    int
+	}{
+		{name: "first delivery", want: 0},
+		{
+			name: "x-death counts add up across retry queues",
+			headers: pubsub.Table{"x-death": []any{
+				death("work.retry.5000ms", "expired", 2),
+				death("work.retry.1000ms", "expired", 1),
+			}},
+			want: 3,
+		},
+		{
+			name: "other queues and reasons do not count",
+			headers: pubsub.Table{"x-death": []any{
+				death("work", "rejected", 4),
+				death("work.retry.1000ms", "rejected", 1),
+				death("work.retry.1000ms", "expired", 1),
+			}},
+			want: 1,
+		},
+		{
+			name:    "attempt header without x-death",
+			headers: pubsub.Table{pubsub.HeaderRetryAttempt: int32(2)},
+			want:    2,
+		},
+		{
+			name: "the higher of the two wins",
+			headers: pubsub.Table{
+				pubsub.HeaderRetryAttempt: int32(1),
+				"x-death":                 []any{death("work.retry.1000ms", "expired", 3)},
+			},
+			want: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pubsub.RetryAttempts(pubsub.Message{Headers: tt.headers}, retryQueues)
+			if got != tt.want {
+				t.Errorf("RetryAttempts = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
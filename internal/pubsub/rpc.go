@@ -0,0 +1,232 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// HeaderErrorCode marks a reply as an error. The body is then the encoded
+// RemoteError rather than a response.
+const HeaderErrorCode = "x-error-code"
+
+// DefaultRequestTimeout bounds a Request whose context has no deadline.
+const DefaultRequestTimeout = 10 * time.Second
+
+const (
+	CodeInternal     = "internal"
+	CodeInvalid      = "invalid"
+	CodeUnauthorized = "unauthorized"
+)
+
+// RemoteError is an error returned by the handler behind Serve. Handlers
+// return one to pick the code the caller sees; any other error reaches
+// the caller with CodeInternal.
+type RemoteError struct {
+	Code    string
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+var ErrClientClosed = errors.New("rpc client closed")
+
+// RPCClient sends requests and waits for their replies, which all arrive
+// on one exclusive queue owned by the client and are matched up by
+// correlation ID.
+type RPCClient struct {
+	pub        Publisher
+	replyQueue string
+	consumer   Consumer
+
+	mu      sync.Mutex
+	pending map[string]chan Delivery
+	closed  bool
+	done    chan struct{}
+}
+
+// NewRPCClient declares the client's reply queue on b and starts
+// listening on it. Requests are published through pub, which can be b
+// itself or a ConfirmingPublisher. Confirms only catch requests the broker
+// can not route: a request to a durable queue that nobody consumes is
+// accepted and sits there, so the caller learns of it only when its
+// deadline passes.
+func NewRPCClient(b Broker, pub Publisher) (*RPCClient, error) {
+	// a fixed name rather than a server-named queue, so the queue can be
+	// redeclared as-is after a reconnect
+	name, err := b.DeclareQueue(QueueSpec{
+		Name:       "rpc.reply." + NewMessageID(),
+		AutoDelete: true,
+		Exclusive:  true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not declare reply queue: %v", err)
+	}
+	consumer, err := b.Consume(name, 0)
+	if err != nil {
+		return nil, fmt.Errorf("could not consume replies: %v", err)
+	}
+
+	c := &RPCClient{
+		pub:        pub,
+		replyQueue: name,
+		consumer:   consumer,
+		pending:    map[string]chan Delivery{},
+		done:       make(chan struct{}),
+	}
+	go c.run()
+	return c, nil
+}
+
+func (c *RPCClient) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.mu.Unlock()
+
+	err := c.consumer.Close()
+	<-c.done
+	return err
+}
+
+func (c *RPCClient) run() {
+	defer close(c.done)
+	for d := range c.consumer.Deliveries() {
+		d.Ack()
+		c.mu.Lock()
+		reply, ok := c.pending[d.CorrelationID]
+		delete(c.pending, d.CorrelationID)
+		c.mu.Unlock()
+		// a reply that arrives after its request timed out is dropped
+		if ok {
+			reply <- d
+		}
+	}
+}
+
+func (c *RPCClient) register(id string) (chan Delivery, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrClientClosed
+	}
+	reply := make(chan Delivery, 1)
+	c.pending[id] = reply
+	return reply, nil
+}
+
+func (c *RPCClient) forget(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, id)
+}
+
+// Request publishes req as JSON and waits for the response. It fails with
+// the context's error when ctx is cancelled or the deadline passes, and
+// with a *RemoteError when the server answered with one.
+func Request[Req, Resp any](ctx context.Context, c *RPCClient, exchange, key string, req Req, opts ...PublishOption) (Resp, error) {
+	var resp Resp
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultRequestTimeout)
+		defer cancel()
+	}
+	deadline, _ := ctx.Deadline()
+
+	id := NewMessageID()
+	reply, err := c.register(id)
+	if err != nil {
+		return resp, err
+	}
+	defer c.forget(id)
+
+	opts = append(opts,
+		WithMessageID(id),
+		WithCorrelationID(id),
+		WithReplyTo(c.replyQueue),
+		WithDeadline(deadline),
+	)
+	err = Publish(ctx, c.pub, JSONCodec{}, exchange, key, req, opts...)
+	if err != nil {
+		return resp, err
+	}
+
+	select {
+	case d := <-reply:
+		if code, ok := d.Headers[HeaderErrorCode].(string); ok {
+			remote := &RemoteError{Code: code}
+			err := decode(d.Message, remote)
+			if err != nil {
+				remote.Message = "undecodable error reply"
+			}
+			return resp, remote
+		}
+		err := decode(d.Message, &resp)
+		if err != nil {
+			return resp, fmt.Errorf("could not decode reply: %v", err)
+		}
+		return resp, nil
+	case <-ctx.Done():
+		return resp, fmt.Errorf("no reply to %s: %w", key, ctx.Err())
+	case <-c.done:
+		return resp, ErrClientClosed
+	}
+}
+
+// Serve answers requests arriving on the queue. The handler's context
+// carries the requester's deadline, and requests whose deadline has
+// already passed are dropped unanswered. Replies are published as JSON on
+// the default exchange to the queue named in the request's reply-to
+// property.
+func Serve[Req, Resp any](
+	ctx context.Context,
+	b Broker,
+	exchange,
+	queueName,
+	key string,
+	simpleQueueType SimpleQueueType,
+	handler func(ctx context.Context, req Req, env Envelope) (Resp, error),
+	opts ...SubscribeOption,
+) (*Subscription, error) {
+	return SubscribeEnvelope(ctx, b, exchange, queueName, key, simpleQueueType, func(req Req, env Envelope) Acktype {
+		hctx := ctx
+		if !env.Deadline.IsZero() {
+			if time.Now().After(env.Deadline) {
+				return Ack
+			}
+			var cancel context.CancelFunc
+			hctx, cancel = context.WithDeadline(ctx, env.Deadline)
+			defer cancel()
+		}
+
+		resp, err := handler(hctx, req, env)
+		// nobody is waiting for the answer any more
+		if env.ReplyTo == "" || hctx.Err() != nil {
+			return Ack
+		}
+
+		var body any = resp
+		replyOpts := []PublishOption{CausedBy(env)}
+		if err != nil {
+			var remote *RemoteError
+			if !errors.As(err, &remote) {
+				remote = &RemoteError{Code: CodeInternal, Message: err.Error()}
+			}
+			body = remote
+			replyOpts = append(replyOpts, withHeader(HeaderErrorCode, remote.Code))
+		}
+		err = Publish(hctx, b, JSONCodec{}, "", env.ReplyTo, body, replyOpts...)
+		if err != nil {
+			log.Printf("could not reply to %s: %v", env.ReplyTo, err)
+		}
+		return Ack
+	}, opts...)
+}
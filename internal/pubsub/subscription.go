@@ -0,0 +1,105 @@
+package pubsub
+
+import (
+	"context"
+)
+
+// DefaultPrefetch is how many unacknowledged deliveries a subscription
+// holds at once unless WithPrefetch says otherwise.
+const DefaultPrefetch = 10
+
+type SubscribeOption func(*subscribeConfig)
+
+type subscribeConfig struct {
+	prefetch   int
+	middleware []Middleware
+	retry      *RetryPolicy
+}
+
+// WithPrefetch limits the number of unacknowledged deliveries; 0 means no
+// limit.
+func WithPrefetch(n int) SubscribeOption {
+	return func(c *subscribeConfig) {
+		c.prefetch = n
+	}
+}
+
+// WithMiddleware wraps the subscription's handler. Middleware from
+// repeated options is applied in order, the first being the outermost.
+func WithMiddleware(mws ...Middleware) SubscribeOption {
+	return func(c *subscribeConfig) {
+		c.middleware = append(c.middleware, mws...)
+	}
+}
+
+// Subscription is a running consumer started by one of the Subscribe
+// helpers. Cancelling the context it was started with, or calling Close,
+// stops it from taking new deliveries; a handler that is already running
+// is allowed to finish and settle its message before the underlying
+// channel is closed.
+type Subscription struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	consumer Consumer
+	done     chan struct{}
+}
+
+func newSubscription(ctx context.Context, consumer Consumer) *Subscription {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Subscription{
+		ctx:      ctx,
+		cancel:   cancel,
+		consumer: consumer,
+		done:     make(chan struct{}),
+	}
+}
+
+// Close stops the subscription and waits for it to drain.
+func (s *Subscription) Close() error {
+	s.cancel()
+	s.Wait()
+	return nil
+}
+
+// Wait blocks until the subscription has stopped, either because it was
+// cancelled or because the broker stopped delivering.
+func (s *Subscription) Wait() {
+	<-s.done
+}
+
+func (s *Subscription) run(handle Handler) {
+	defer s.finish()
+	for {
+		d, ok := s.next()
+		if !ok {
+			return
+		}
+		switch handle(d) {
+		case Ack:
+			d.Ack()
+		case NackRequeue:
+			d.Nack(true)
+		case NackDiscard:
+			d.Nack(false)
+		}
+	}
+}
+
+func (s *Subscription) next() (Delivery, bool) {
+	// give cancellation priority over deliveries that are already queued
+	if s.ctx.Err() != nil {
+		return Delivery{}, false
+	}
+	select {
+	case d, ok := <-s.consumer.Deliveries():
+		return d, ok
+	case <-s.ctx.Done():
+		return Delivery{}, false
+	}
+}
+
+func (s *Subscription) finish() {
+	s.consumer.Close()
+	s.cancel()
+	close(s.done)
+}
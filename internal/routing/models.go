@@ -1,13 +1,50 @@
 package routing
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type PlayingState struct {
 	IsPaused bool
 }
 
+// TurnState announces the start or the end of a turn in a turn-based
+// game. Players lists who may give orders during the turn.
+type TurnState struct {
+	Turn    int
+	Started bool
+	Ends    time.Time
+	Players []string
+}
+
 type GameLog struct {
 	CurrentTime time.Time
 	Message     string
 	Username    string
 }
+
+// GameOver announces the end of the game. Winner is empty if nobody won.
+type GameOver struct {
+	Winner     string
+	Reason     string
+	Scoreboard []Score
+}
+
+type Score struct {
+	Username string
+	Regions  int
+	Units    int
+	Treasury int
+	Points   int
+}
+
+// ScoreboardLines renders the scoreboard, one player per line.
+func (g GameOver) ScoreboardLines() []string {
+	lines := make([]string, len(g.Scoreboard))
+	for i, s := range g.Scoreboard {
+		lines[i] = fmt.Sprintf("%d. %s: %d points (%d regions, %d units, treasury %d)",
+			i+1, s.Username, s.Points, s.Regions, s.Units, s.Treasury)
+	}
+	return lines
+}
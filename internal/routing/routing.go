@@ -6,6 +6,13 @@ const (
 	WarRecognitionsPrefix = "war"
 
 	PauseKey = "pause"
+	TurnKey  = "turn"
+
+	GameOverKey = "game_over"
+
+	CommandsPrefix = "commands"
+	StatePrefix    = "state"
+	JoinPrefix     = "join"
 
 	GameLogSlug = "game_logs"
 )
@@ -15,3 +22,9 @@ const (
 	ExchangePerilTopic      = "peril_topic"
 	ExchangePerilDeadLetter = "peril_dlx"
 )
+
+const (
+	DeadLetterQueue = "peril_dlq"
+	CommandsQueue   = "commands"
+	JoinQueue       = "join"
+)
@@ -0,0 +1,88 @@
+package topology
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Diff lists what would have to change on a broker holding have for it to
+// match want. Lines start with "+" for something missing, "-" for
+// something the spec does not mention and "~" for something declared
+// differently. Differences marked "~" cannot be fixed by apply: RabbitMQ
+// refuses to redeclare an entity with other properties, so it has to be
+// deleted first.
+func Diff(want, have Spec) []string {
+	lines := []string{}
+
+	haveExchanges := map[string]Exchange{}
+	for _, ex := range have.Exchanges {
+		haveExchanges[ex.Name] = ex
+	}
+	for _, ex := range want.Exchanges {
+		got, ok := haveExchanges[ex.Name]
+		delete(haveExchanges, ex.Name)
+		if !ok {
+			lines = append(lines, fmt.Sprintf("+ exchange %s (%s)", ex.Name, ex.Kind))
+		} else if got != ex {
+			lines = append(lines, fmt.Sprintf("~ exchange %s: have %+v, want %+v", ex.Name, got, ex))
+		}
+	}
+	for _, ex := range sortedExtras(haveExchanges) {
+		lines = append(lines, "- exchange "+ex)
+	}
+
+	haveQueues := map[string]Queue{}
+	for _, q := range have.Queues {
+		haveQueues[q.Name] = q
+	}
+	for _, q := range want.Queues {
+		got, ok := haveQueues[q.Name]
+		delete(haveQueues, q.Name)
+		if !ok {
+			lines = append(lines, "+ queue "+q.Name)
+		} else if !sameQueue(got, q) {
+			lines = append(lines, fmt.Sprintf("~ queue %s: have %+v, want %+v", q.Name, got, q))
+		}
+	}
+	for _, q := range sortedExtras(haveQueues) {
+		lines = append(lines, "- queue "+q)
+	}
+
+	haveBindings := map[Binding]struct{}{}
+	for _, b := range have.Bindings {
+		haveBindings[b] = struct{}{}
+	}
+	for _, b := range want.Bindings {
+		_, ok := haveBindings[b]
+		delete(haveBindings, b)
+		if !ok {
+			lines = append(lines, fmt.Sprintf("+ binding %s -> %s [%s]", b.Exchange, b.Queue, b.Key))
+		}
+	}
+	extra := Spec{}
+	for b := range haveBindings {
+		extra.Bindings = append(extra.Bindings, b)
+	}
+	extra.sort()
+	for _, b := range extra.Bindings {
+		lines = append(lines, fmt.Sprintf("- binding %s -> %s [%s]", b.Exchange, b.Queue, b.Key))
+	}
+	return lines
+}
+
+func sameQueue(a, b Queue) bool {
+	if len(a.Arguments) == 0 && len(b.Arguments) == 0 {
+		a.Arguments, b.Arguments = nil, nil
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func sortedExtras[T any](m map[string]T) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
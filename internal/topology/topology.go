@@ -0,0 +1,223 @@
+// Package topology describes the exchanges, queues and bindings Peril
+// expects on the broker, and provisions or checks them.
+package topology
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+)
+
+//go:embed peril.json
+var defaultSpec []byte
+
+type Spec struct {
+	Exchanges []Exchange `json:"exchanges"`
+	Queues    []Queue    `json:"queues"`
+	Bindings  []Binding  `json:"bindings"`
+}
+
+type Exchange struct {
+	Name    string `json:"name"`
+	Kind    string `json:"kind"`
+	Durable bool   `json:"durable"`
+}
+
+type Queue struct {
+	Name       string         `json:"name"`
+	Durable    bool           `json:"durable"`
+	AutoDelete bool           `json:"auto_delete,omitempty"`
+	Exclusive  bool           `json:"exclusive,omitempty"`
+	Arguments  map[string]any `json:"arguments,omitempty"`
+}
+
+type Binding struct {
+	Queue    string `json:"queue"`
+	Exchange string `json:"exchange"`
+	Key      string `json:"key"`
+}
+
+// Default returns the topology the Peril server and clients are built
+// against.
+func Default() Spec {
+	spec, err := Parse(defaultSpec)
+	if err != nil {
+		panic(fmt.Sprintf("embedded topology is invalid: %v", err))
+	}
+	return spec
+}
+
+func Load(path string) (Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Spec{}, fmt.Errorf("could not read topology file: %v", err)
+	}
+	spec, err := Parse(data)
+	if err != nil {
+		return Spec{}, fmt.Errorf("%s: %v", path, err)
+	}
+	return spec, nil
+}
+
+func Parse(data []byte) (Spec, error) {
+	var spec Spec
+	err := json.Unmarshal(data, &spec)
+	if err != nil {
+		return Spec{}, fmt.Errorf("could not parse topology: %v", err)
+	}
+	for i := range spec.Queues {
+		spec.Queues[i].Arguments = normalizeArguments(spec.Queues[i].Arguments)
+	}
+	return spec, spec.Validate()
+}
+
+func (s Spec) Validate() error {
+	exchanges := map[string]struct{}{}
+	for _, ex := range s.Exchanges {
+		if ex.Name == "" {
+			return fmt.Errorf("exchange without a name")
+		}
+		if _, ok := exchanges[ex.Name]; ok {
+			return fmt.Errorf("exchange %s is declared twice", ex.Name)
+		}
+		switch ex.Kind {
+		case pubsub.ExchangeDirect, pubsub.ExchangeTopic, pubsub.ExchangeFanout:
+		default:
+			return fmt.Errorf("exchange %s has unsupported kind %q", ex.Name, ex.Kind)
+		}
+		exchanges[ex.Name] = struct{}{}
+	}
+
+	queues := map[string]struct{}{}
+	for _, q := range s.Queues {
+		if q.Name == "" {
+			return fmt.Errorf("queue without a name")
+		}
+		if _, ok := queues[q.Name]; ok {
+			return fmt.Errorf("queue %s is declared twice", q.Name)
+		}
+		queues[q.Name] = struct{}{}
+	}
+
+	for _, b := range s.Bindings {
+		if _, ok := exchanges[b.Exchange]; !ok {
+			return fmt.Errorf("binding of %s refers to unknown exchange %s", b.Queue, b.Exchange)
+		}
+		if _, ok := queues[b.Queue]; !ok {
+			return fmt.Errorf("binding from %s refers to unknown queue %s", b.Exchange, b.Queue)
+		}
+	}
+	return nil
+}
+
+func (s Spec) MarshalIndent() ([]byte, error) {
+	s.sort()
+	return json.MarshalIndent(s, "", "  ")
+}
+
+func (s *Spec) sort() {
+	sort.Slice(s.Exchanges, func(i, j int) bool {
+		return s.Exchanges[i].Name < s.Exchanges[j].Name
+	})
+	sort.Slice(s.Queues, func(i, j int) bool {
+		return s.Queues[i].Name < s.Queues[j].Name
+	})
+	sort.Slice(s.Bindings, func(i, j int) bool {
+		a, b := s.Bindings[i], s.Bindings[j]
+		if a.Exchange != b.Exchange {
+			return a.Exchange < b.Exchange
+		}
+		if a.Queue != b.Queue {
+			return a.Queue < b.Queue
+		}
+		return a.Key < b.Key
+	})
+}
+
+// Apply declares everything in the spec. Declarations are idempotent, so
+// applying the same spec twice is harmless; applying one that conflicts
+// with what is on the broker fails with the broker's error.
+func Apply(t pubsub.Topology, spec Spec) error {
+	for _, ex := range spec.Exchanges {
+		err := t.DeclareExchange(pubsub.ExchangeSpec{
+			Name:    ex.Name,
+			Kind:    ex.Kind,
+			Durable: ex.Durable,
+		})
+		if err != nil {
+			return fmt.Errorf("could not declare exchange %s: %v", ex.Name, err)
+		}
+	}
+	for _, q := range spec.Queues {
+		_, err := t.DeclareQueue(pubsub.QueueSpec{
+			Name:       q.Name,
+			Durable:    q.Durable,
+			AutoDelete: q.AutoDelete,
+			Exclusive:  q.Exclusive,
+			Args:       pubsub.Table(q.Arguments),
+		})
+		if err != nil {
+			return fmt.Errorf("could not declare queue %s: %v", q.Name, err)
+		}
+	}
+	for _, b := range spec.Bindings {
+		err := t.BindQueue(b.Queue, b.Key, b.Exchange)
+		if err != nil {
+			return fmt.Errorf("could not bind %s to %s: %v", b.Queue, b.Exchange, err)
+		}
+	}
+	return nil
+}
+
+// Check verifies that every exchange and queue in the spec exists on the
+// broker. Bindings cannot be inspected over AMQP and are not checked.
+func Check(i pubsub.Inspector, spec Spec) error {
+	missing := []string{}
+	for _, ex := range spec.Exchanges {
+		ok, err := i.ExchangeExists(ex.Name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			missing = append(missing, "exchange "+ex.Name)
+		}
+	}
+	for _, q := range spec.Queues {
+		ok, err := i.QueueExists(q.Name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			missing = append(missing, "queue "+q.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingError{Missing: missing}
+	}
+	return nil
+}
+
+type MissingError struct {
+	Missing []string
+}
+
+func (e *MissingError) Error() string {
+	return fmt.Sprintf("broker is missing %s; run `peril-topology apply`", strings.Join(e.Missing, ", "))
+}
+
+// normalizeArguments turns whole JSON numbers into integers, which is what
+// RabbitMQ expects for arguments such as x-message-ttl.
+func normalizeArguments(args map[string]any) map[string]any {
+	for k, v := range args {
+		if f, ok := v.(float64); ok && f == math.Trunc(f) {
+			args[k] = int64(f)
+		}
+	}
+	return args
+}
@@ -0,0 +1,132 @@
+package topology
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub/memory"
+)
+
+func TestParseNormalizesArguments(t *testing.T) {
+	spec, err := Parse([]byte(`{
+  "queues": [{
+    "name": "q",
+    "arguments": {"x-message-ttl": 1000, "x-ratio": 0.5, "x-dead-letter-exchange": "dlx"}
+  }]
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"x-message-ttl":          int64(1000),
+		"x-ratio":                0.5,
+		"x-dead-letter-exchange": "dlx",
+	}
+	if got := spec.Queues[0].Arguments; !reflect.DeepEqual(got, want) {
+		t.Errorf("arguments = %#v, want %#v", got, want)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	base := Spec{
+		Exchanges: []Exchange{{Name: "peril_topic", Kind: "topic", Durable: true}},
+		Queues: []Queue{
+			{Name: "game_logs", Durable: true, Arguments: map[string]any{"x-dead-letter-exchange": "peril_dlx"}},
+			{Name: "plain", Durable: true},
+		},
+		Bindings: []Binding{{Queue: "game_logs", Exchange: "peril_topic", Key: "game_logs.*"}},
+	}
+
+	tests := []struct {
+		name string
+		have func(*Spec)
+		want []string
+	}{
+		{
+			name: "same",
+			have: func(s *Spec) {},
+			want: []string{},
+		},
+		{
+			name: "empty arguments match none",
+			have: func(s *Spec) { s.Queues[1].Arguments = map[string]any{} },
+			want: []string{},
+		},
+		{
+			name: "missing",
+			have: func(s *Spec) { *s = Spec{} },
+			want: []string{
+				"+ exchange peril_topic (topic)",
+				"+ queue game_logs",
+				"+ queue plain",
+				"+ binding peril_topic -> game_logs [game_logs.*]",
+			},
+		},
+		{
+			name: "extra",
+			have: func(s *Spec) {
+				s.Exchanges = append(s.Exchanges, Exchange{Name: "old", Kind: "direct"})
+				s.Queues = append(s.Queues, Queue{Name: "zombie"}, Queue{Name: "orphan"})
+				s.Bindings = append(s.Bindings, Binding{Queue: "plain", Exchange: "peril_topic", Key: "#"})
+			},
+			want: []string{
+				"- exchange old",
+				"- queue orphan",
+				"- queue zombie",
+				"- binding peril_topic -> plain [#]",
+			},
+		},
+		{
+			name: "declared differently",
+			have: func(s *Spec) {
+				s.Exchanges[0].Durable = false
+				s.Queues[0].Arguments = map[string]any{"x-dead-letter-exchange": "other"}
+			},
+			want: []string{
+				"~ exchange peril_topic: have {Name:peril_topic Kind:topic Durable:false}, want {Name:peril_topic Kind:topic Durable:true}",
+				"~ queue game_logs: have {Name:game_logs Durable:true AutoDelete:false Exclusive:false Arguments:map[x-dead-letter-exchange:other]}, want {Name:game_logs Durable:true AutoDelete:false Exclusive:false Arguments:map[x-dead-letter-exchange:peril_dlx]}",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have := copySpec(base)
+			tt.have(&have)
+			got := Diff(base, have)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyDefault(t *testing.T) {
+	b := memory.New()
+	for i := 0; i < 2; i++ {
+		if err := Apply(b, Default()); err != nil {
+			t.Fatalf("apply %d: %v", i+1, err)
+		}
+	}
+	if err := Check(b, Default()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func copySpec(s Spec) Spec {
+	c := Spec{
+		Exchanges: append([]Exchange{}, s.Exchanges...),
+		Queues:    append([]Queue{}, s.Queues...),
+		Bindings:  append([]Binding{}, s.Bindings...),
+	}
+	for i, q := range c.Queues {
+		if q.Arguments == nil {
+			continue
+		}
+		args := map[string]any{}
+		for k, v := range q.Arguments {
+			args[k] = v
+		}
+		c.Queues[i].Arguments = args
+	}
+	return c
+}
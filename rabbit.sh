@@ -5,6 +5,14 @@ case "$1" in
         echo "Starting RabbitMQ container..."
         docker run -d --rm --name rabbitmq -p 5672:5672 -p 15672:15672 rabbitmq:3.13-management
         ;;
+    start-tls)
+        echo "Starting RabbitMQ container with TLS on port 5671..."
+        docker run -d --rm --name rabbitmq -p 5671:5671 -p 5672:5672 -p 15672:15672 \
+            -v "$(pwd)/tls/certs:/etc/rabbitmq/certs:ro" \
+            -v "$(pwd)/tls/rabbitmq.conf:/etc/rabbitmq/conf.d/20-tls.conf:ro" \
+            rabbitmq:3.13-management \
+            bash -c "rabbitmq-plugins enable --offline rabbitmq_auth_mechanism_ssl && exec docker-entrypoint.sh rabbitmq-server"
+        ;;
     stop)
         echo "Stopping RabbitMQ container..."
         docker stop rabbitmq
@@ -14,6 +22,6 @@ case "$1" in
         docker logs -f rabbitmq
         ;;
     *)
-        echo "Usage: $0 {start|stop|logs}"
+        echo "Usage: $0 {start|start-tls|stop|logs}"
         exit 1
 esac
@@ -0,0 +1,40 @@
+#!/bin/bash
+# Generates a throwaway CA plus a broker and a client certificate signed by
+# it, for trying amqps and EXTERNAL authentication against a local broker.
+#
+#   ./tls/gen-certs.sh [client-name]
+#
+# The client certificate's CN is the RabbitMQ user EXTERNAL logs in as
+# (default "peril"); create that user with `rabbitmqctl add_user` and grant
+# it permissions before connecting.
+
+set -euo pipefail
+
+client=${1:-peril}
+dir=$(dirname "$0")/certs
+mkdir -p "$dir"
+cd "$dir"
+
+openssl req -x509 -newkey rsa:2048 -nodes -days 365 \
+    -subj "/CN=Peril Test CA" \
+    -keyout ca.key -out ca.pem
+
+openssl req -newkey rsa:2048 -nodes \
+    -subj "/CN=localhost" \
+    -keyout server.key -out server.csr
+openssl x509 -req -in server.csr -CA ca.pem -CAkey ca.key -CAcreateserial -days 365 \
+    -extfile <(printf "subjectAltName=DNS:localhost,IP:127.0.0.1\nextendedKeyUsage=serverAuth") \
+    -out server.pem
+
+openssl req -newkey rsa:2048 -nodes \
+    -subj "/CN=$client" \
+    -keyout client.key -out client.csr
+openssl x509 -req -in client.csr -CA ca.pem -CAkey ca.key -CAcreateserial -days 365 \
+    -extfile <(printf "extendedKeyUsage=clientAuth") \
+    -out client.pem
+
+rm -f server.csr client.csr ca.srl
+# the broker runs as a different user inside the container
+chmod 644 server.key
+
+echo "Wrote CA, server and client ($client) certificates to $dir"